@@ -1,18 +1,22 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
 
+	"TGBOT2/internal/calendar"
 	"TGBOT2/internal/config"
+	"TGBOT2/internal/engine"
 	"TGBOT2/internal/storage"
-	"TGBOT2/internal/tg"
 )
 
 func main() {
@@ -33,90 +37,49 @@ func main() {
 		log.Fatalf("failed to create bot: %v", err)
 	}
 	bot.Debug = true
-	log.Printf("authorized as @%s", bot.Self.UserName)
-	go startDailyDeadlineReminderBot1(bot, db)
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	log.Printf("bot1 authorized as @%s", bot.Self.UserName)
 
-	for upd := range updates {
-		// 1) callback-кнопки
-		if upd.CallbackQuery != nil {
-			// выбор компании пользователем
-			// кнопки рассылки навигатора
-			tg.HandleBroadcastCallback(bot, db, cfg, upd.CallbackQuery)
-			continue
-		}
-
-		if upd.Message == nil {
-			continue
-		}
-		m := upd.Message
-
-		// /chatid для любого чата
-		if m.IsCommand() && m.Command() == "chatid" {
-			chatID := m.Chat.ID
-			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("chat_id = %d", chatID))
-			_, _ = bot.Send(msg)
-			continue
-		}
-
-		// 2) бухгалтерские группы или чат навигатора
-		if m.Chat != nil && (m.Chat.ID == cfg.Bot1NavigatorChatID ||
-			m.Chat.ID == cfg.Accounting1ChatID ||
-			m.Chat.ID == cfg.Accounting2ChatID ||
-			m.Chat.ID == cfg.Accounting3ChatID ||
-			m.Chat.ID == cfg.Accounting4ChatID) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-			// навигатор: /start, /broadcast, кнопка "📨 Рассылка" и т.п.
-			tg.HandleNavigatorBroadcast(bot, db, cfg, m)
-			// ответы бухгалтеров/навигатора пользователю (reply)
-			tg.HandleSupportReply(bot, db, cfg, m)
-			continue
-		}
-
-		// 3) личка – пользователи
-		if m.Chat != nil && m.Chat.IsPrivate() {
-			tg.HandleUserMessage(bot, db, cfg, m)
-			continue
-		}
-	}
-}
-
-func startDailyDeadlineReminderBot1(bot *tgbotapi.BotAPI, db *sql.DB) {
-	loc, err := time.LoadLocation("Europe/Moscow")
+	cal, err := calendar.Load(cfg.ProductionCalendarPath)
 	if err != nil {
-		loc = time.FixedZone("MSK", 3*60*60)
+		log.Printf("bot1: %v (используем пн-пт без праздников)", err)
 	}
 
-	const text = "Уважаемые партнёры, через 15 минут заканчивается приём заявок"
-	lastSentDate := ""
-
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		now := time.Now().In(loc)
-		if now.Hour() != 15 || now.Minute() != 35 {
-			continue
-		}
-
-		today := now.Format("2006-01-02")
-		if lastSentDate == today {
-			continue
-		}
-		lastSentDate = today
-
-		chatIDs, err := storage.ListAllowedUserChatIDs(db)
-		if err != nil {
-			log.Printf("reminder bot1: ListAllowedUserChatIDs error: %v", err)
-			continue
-		}
-
-		for _, cid := range chatIDs {
-			_, _ = bot.Send(tgbotapi.NewMessage(cid, text))
+	e := engine.New(bot, db, cfg, engine.ProfileBot1(cfg), cal, nil)
+
+	webhookErr := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		e.StartScheduledBroadcasts(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		if cfg.WebhookListenAddr != "" {
+			// упавший webhook-сервер останавливает бота как SIGTERM: дренаж, закрытие БД, потом выход с ошибкой
+			if err := engine.ServeWebhooks(ctx, cfg, []*engine.Bot{e}); err != nil {
+				webhookErr <- err
+				stop()
+			}
+			return
 		}
+		e.Run(ctx) // на SIGTERM: бросаем long poll + дожидаемся принятых апдейтов
+	}()
+
+	<-ctx.Done()
+	log.Printf("bot1: shutting down")
+	// обработчики сами отменяются через ShutdownTimeout, запас — на выход из Send
+	if !engine.Drain(&wg, cfg.ShutdownTimeout+10*time.Second) {
+		log.Printf("bot1: drain timeout, exiting anyway")
+	}
 
-		log.Printf("reminder bot1 sent to %d users", len(chatIDs))
+	select {
+	case err := <-webhookErr:
+		_ = db.Close()
+		log.Fatalf("bot1: %v", err)
+	default:
 	}
 }
@@ -1,18 +1,22 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
 
+	"TGBOT2/internal/calendar"
 	"TGBOT2/internal/config"
+	"TGBOT2/internal/engine"
 	"TGBOT2/internal/storage"
-	"TGBOT2/internal/tg2"
 )
 
 func main() {
@@ -34,78 +38,48 @@ func main() {
 	}
 	bot.Debug = true
 	log.Printf("bot2 authorized as @%s", bot.Self.UserName)
-	go startDailyDeadlineReminderBot2(bot, db)
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
 
-	for upd := range updates {
-		if upd.CallbackQuery != nil {
-			tg2.HandleBroadcastCallback(bot, db, cfg, upd.CallbackQuery)
-			continue
-		}
-
-		if upd.Message == nil {
-			continue
-		}
-		m := upd.Message
-
-		// /chatid
-		if m.IsCommand() && m.Command() == "chatid" {
-			chatID := m.Chat.ID
-			_, _ = bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("chat_id = %d", chatID)))
-			continue
-		}
-
-		// бухгалтерская группа или чат навигатора
-		if m.Chat != nil && (m.Chat.ID == cfg.Bot2AccountingChatID || m.Chat.ID == cfg.Bot2NavigatorChatID) {
-			tg2.HandleNavigatorBroadcast(bot, db, cfg, m)
-			tg2.HandleSupportReply(bot, db, cfg, m)
-			continue
-		}
-
-		// личка — пользователи
-		if m.Chat != nil && m.Chat.IsPrivate() {
-			tg2.HandleUserMessage(bot, db, cfg, m)
-			continue
-		}
-	}
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-func startDailyDeadlineReminderBot2(bot *tgbotapi.BotAPI, db *sql.DB) {
-	loc, err := time.LoadLocation("Europe/Moscow")
+	cal, err := calendar.Load(cfg.ProductionCalendarPath)
 	if err != nil {
-		loc = time.FixedZone("MSK", 3*60*60)
+		log.Printf("bot2: %v (используем пн-пт без праздников)", err)
 	}
 
-	const text = "Уважаемые партнёры, через 15 минут заканчивается приём заявок"
-	lastSentDate := ""
-
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		now := time.Now().In(loc)
-		if now.Hour() != 15 || now.Minute() != 35 {
-			continue
-		}
-
-		today := now.Format("2006-01-02")
-		if lastSentDate == today {
-			continue
-		}
-		lastSentDate = today
-
-		chatIDs, err := storage.ListAllowedUserChatIDs(db)
-		if err != nil {
-			log.Printf("reminder bot2: ListAllowedUserChatIDs error: %v", err)
-			continue
-		}
-
-		for _, cid := range chatIDs {
-			_, _ = bot.Send(tgbotapi.NewMessage(cid, text))
+	e := engine.New(bot, db, cfg, engine.ProfileBot2(cfg), cal, nil)
+
+	webhookErr := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		e.StartScheduledBroadcasts(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		if cfg.WebhookListenAddr != "" {
+			// упавший webhook-сервер останавливает бота как SIGTERM: дренаж, закрытие БД, потом выход с ошибкой
+			if err := engine.ServeWebhooks(ctx, cfg, []*engine.Bot{e}); err != nil {
+				webhookErr <- err
+				stop()
+			}
+			return
 		}
+		e.Run(ctx) // на SIGTERM: бросаем long poll + дожидаемся принятых апдейтов
+	}()
+
+	<-ctx.Done()
+	log.Printf("bot2: shutting down")
+	// обработчики сами отменяются через ShutdownTimeout, запас — на выход из Send
+	if !engine.Drain(&wg, cfg.ShutdownTimeout+10*time.Second) {
+		log.Printf("bot2: drain timeout, exiting anyway")
+	}
 
-		log.Printf("reminder bot2 sent to %d users", len(chatIDs))
+	select {
+	case err := <-webhookErr:
+		_ = db.Close()
+		log.Fatalf("bot2: %v", err)
+	default:
 	}
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/joho/godotenv"
+
+	"TGBOT2/internal/calendar"
+	"TGBOT2/internal/companylookup"
+	"TGBOT2/internal/config"
+	"TGBOT2/internal/engine"
+	"TGBOT2/internal/storage"
+)
+
+// botd: все боты в одном процессе с общей БД.
+// Какие боты запускать — BOTD_BOTS (например "bot1,bot3"), по умолчанию все, у кого задан токен.
+func main() {
+	_ = godotenv.Load()
+	cfg := config.MustLoad()
+	if strings.TrimSpace(cfg.AccessPassword) == "" {
+		log.Fatal("ACCESS_PASSWORD is not set")
+	}
+
+	profiles := selectProfiles(cfg)
+	if len(profiles) == 0 {
+		log.Fatal("no bots to run: set BOTD_BOTS and BOTn_TOKEN")
+	}
+
+	db := storage.MustOpen(cfg.DBPath)
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// календарь общий: /calendar reload в любом навигаторском чате обновляет его для всех ботов
+	cal, err := calendar.Load(cfg.ProductionCalendarPath)
+	if err != nil {
+		log.Printf("botd: %v (используем пн-пт без праздников)", err)
+	}
+
+	// поиск контрагентов нужен только ботам с заявками; ошибка настройки — не стартуем
+	var lookup companylookup.CompanyLookup
+	for _, p := range profiles {
+		if p.Applications {
+			if lookup, err = engine.NewCompanyLookup(cfg); err != nil {
+				log.Fatalf("COMPANY_LOOKUP: %v", err)
+			}
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	var bots []*engine.Bot
+	for _, p := range profiles {
+		bot, err := tgbotapi.NewBotAPI(p.Token)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", p.Name, err)
+		}
+		log.Printf("%s authorized as @%s", p.Name, bot.Self.UserName)
+
+		e := engine.New(bot, db, cfg, p, cal, lookup)
+		if p.Applications {
+			e.PurgeExpiredDrafts()
+		}
+
+		bots = append(bots, e)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.StartScheduledBroadcasts(ctx)
+		}()
+	}
+
+	// апдейты: один webhook-сервер на все боты или long polling у каждого
+	webhookErr := make(chan error, 1)
+	if cfg.WebhookListenAddr != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// упавший webhook-сервер останавливает всех как SIGTERM: дренаж, закрытие БД, потом выход с ошибкой
+			if err := engine.ServeWebhooks(ctx, cfg, bots); err != nil {
+				webhookErr <- err
+				stop()
+			}
+		}()
+	} else {
+		for _, e := range bots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e.Run(ctx)
+			}()
+		}
+	}
+
+	<-ctx.Done()
+	log.Printf("botd: shutting down, waiting for in-flight handlers")
+	drained := engine.Drain(&wg, cfg.ShutdownTimeout+10*time.Second)
+	if !drained {
+		log.Printf("botd: drain timeout, exiting anyway")
+	}
+
+	select {
+	case err := <-webhookErr:
+		_ = db.Close()
+		log.Fatalf("botd: %v", err)
+	default:
+	}
+	if drained {
+		log.Printf("botd: stopped")
+	}
+}
+
+func selectProfiles(cfg *config.Config) []engine.Profile {
+	if len(cfg.BotdBots) == 0 {
+		var out []engine.Profile
+		for _, p := range engine.AllProfiles(cfg) {
+			if p.Token != "" {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	var out []engine.Profile
+	seen := map[string]bool{}
+	for _, name := range cfg.BotdBots {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		p, ok := engine.ProfileByName(cfg, name)
+		if !ok {
+			log.Fatalf("BOTD_BOTS: unknown bot %q", name)
+		}
+		if p.Token == "" {
+			log.Fatalf("BOTD_BOTS: %s token is not set", name)
+		}
+		out = append(out, p)
+	}
+	return out
+}
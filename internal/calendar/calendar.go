@@ -0,0 +1,239 @@
+// Package calendar — производственный календарь: рабочие дни, праздники, сокращённые дни
+// и время окончания приёма заявок. Данные лежат в JSON (assets/production_calendar.json),
+// администратор правит файл и перечитывает его командой навигатора без перезапуска.
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// File — формат JSON-файла.
+type File struct {
+	// окончание приёма заявок в обычный рабочий день и в сокращённый предпраздничный, "15:50"
+	Deadline         string `json:"deadline"`
+	ShortDayDeadline string `json:"short_day_deadline"`
+
+	Holidays        []string          `json:"holidays"`         // нерабочие дни (включая переносы), "2026-01-01"
+	ShortDays       []string          `json:"short_days"`       // сокращённые рабочие дни
+	WorkingWeekends []string          `json:"working_weekends"` // рабочие субботы/воскресенья
+	Deadlines       map[string]string `json:"deadlines"`        // особое время для конкретной даты
+}
+
+// Calendar безопасен для одновременного использования; Reload подменяет данные целиком.
+type Calendar struct {
+	path string
+	loc  *time.Location
+
+	mu              sync.RWMutex
+	deadline        clock
+	shortDeadline   clock
+	holidays        map[string]bool
+	shortDays       map[string]bool
+	workingWeekends map[string]bool
+	deadlines       map[string]clock
+	loadedAt        time.Time
+	years           []int
+}
+
+type clock struct{ h, m int }
+
+func (c clock) String() string { return fmt.Sprintf("%02d:%02d", c.h, c.m) }
+
+// Load читает календарь из path (даты — по Москве). Даже при ошибке возвращает рабочий
+// календарь (пн-пт рабочие, дедлайн 15:50), чтобы бот не падал из-за битого файла.
+func Load(path string) (*Calendar, error) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		loc = time.FixedZone("MSK", 3*60*60)
+	}
+	c := &Calendar{path: path, loc: loc}
+	c.apply(&parsed{deadline: clock{15, 50}, shortDeadline: clock{14, 50}})
+	return c, c.Reload()
+}
+
+// Path — откуда читается календарь.
+func (c *Calendar) Path() string { return c.path }
+
+// Reload перечитывает файл. При ошибке остаются прежние данные.
+func (c *Calendar) Reload() error {
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("read production calendar: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return fmt.Errorf("parse production calendar %s: %w", c.path, err)
+	}
+	p, err := parse(&f)
+	if err != nil {
+		return fmt.Errorf("production calendar %s: %w", c.path, err)
+	}
+	c.apply(p)
+	return nil
+}
+
+type parsed struct {
+	deadline        clock
+	shortDeadline   clock
+	holidays        map[string]bool
+	shortDays       map[string]bool
+	workingWeekends map[string]bool
+	deadlines       map[string]clock
+	years           []int
+}
+
+func parse(f *File) (*parsed, error) {
+	p := &parsed{}
+	var err error
+	if p.deadline, err = parseClock(f.Deadline); err != nil {
+		return nil, fmt.Errorf("deadline: %w", err)
+	}
+	if p.shortDeadline, err = parseClock(f.ShortDayDeadline); err != nil {
+		return nil, fmt.Errorf("short_day_deadline: %w", err)
+	}
+
+	years := map[int]bool{}
+	if p.holidays, err = parseDates(f.Holidays, years); err != nil {
+		return nil, fmt.Errorf("holidays: %w", err)
+	}
+	if p.shortDays, err = parseDates(f.ShortDays, years); err != nil {
+		return nil, fmt.Errorf("short_days: %w", err)
+	}
+	if p.workingWeekends, err = parseDates(f.WorkingWeekends, years); err != nil {
+		return nil, fmt.Errorf("working_weekends: %w", err)
+	}
+
+	p.deadlines = map[string]clock{}
+	for day, s := range f.Deadlines {
+		if _, err := time.Parse(dateLayout, day); err != nil {
+			return nil, fmt.Errorf("deadlines: bad date %q", day)
+		}
+		cl, err := parseClock(s)
+		if err != nil {
+			return nil, fmt.Errorf("deadlines %s: %w", day, err)
+		}
+		p.deadlines[day] = cl
+	}
+
+	for y := range years {
+		p.years = append(p.years, y)
+	}
+	sort.Ints(p.years)
+	return p, nil
+}
+
+func (c *Calendar) apply(p *parsed) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = p.deadline
+	c.shortDeadline = p.shortDeadline
+	c.holidays = p.holidays
+	c.shortDays = p.shortDays
+	c.workingWeekends = p.workingWeekends
+	c.deadlines = p.deadlines
+	c.years = p.years
+	c.loadedAt = time.Now()
+}
+
+// IsWorkingDay: рабочий ли день (дата берётся в часовом поясе календаря).
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isWorkingDayLocked(t.In(c.loc))
+}
+
+func (c *Calendar) isWorkingDayLocked(t time.Time) bool {
+	day := t.Format(dateLayout)
+	if c.holidays[day] {
+		return false
+	}
+	if c.workingWeekends[day] {
+		return true
+	}
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// IsShortDay: сокращённый предпраздничный день.
+func (c *Calendar) IsShortDay(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.shortDays[t.In(c.loc).Format(dateLayout)]
+}
+
+// Deadline — окончание приёма заявок в день t. false — день нерабочий.
+func (c *Calendar) Deadline(t time.Time) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	t = t.In(c.loc)
+	if !c.isWorkingDayLocked(t) {
+		return time.Time{}, false
+	}
+	day := t.Format(dateLayout)
+	cl := c.deadline
+	if c.shortDays[day] {
+		cl = c.shortDeadline
+	}
+	if d, ok := c.deadlines[day]; ok {
+		cl = d
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), cl.h, cl.m, 0, 0, c.loc), true
+}
+
+// NextDeadline — ближайшее окончание приёма строго после t.
+func (c *Calendar) NextDeadline(t time.Time) time.Time {
+	day := t.In(c.loc)
+	for i := 0; i < 366; i++ {
+		if d, ok := c.Deadline(day); ok && d.After(t) {
+			return d
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.loc)
+	}
+	return time.Time{}
+}
+
+// NextWorkingDay — следующий рабочий день после даты t (полночь этого дня).
+func (c *Calendar) NextWorkingDay(t time.Time) time.Time {
+	t = t.In(c.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+	for i := 0; i < 366 && !c.IsWorkingDay(day); i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// Info — для команды навигатора: когда загружен и какие годы описаны.
+func (c *Calendar) Info() (loadedAt time.Time, years []int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loadedAt, append([]int(nil), c.years...)
+}
+
+func parseClock(s string) (clock, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return clock{}, fmt.Errorf("bad time %q, want HH:MM", s)
+	}
+	return clock{t.Hour(), t.Minute()}, nil
+}
+
+func parseDates(list []string, years map[int]bool) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, s := range list {
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("bad date %q, want YYYY-MM-DD", s)
+		}
+		out[s] = true
+		years[t.Year()] = true
+	}
+	return out, nil
+}
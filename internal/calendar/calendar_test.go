@@ -0,0 +1,145 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testJSON = `{
+  "deadline": "15:50",
+  "short_day_deadline": "14:50",
+  "holidays": ["2026-11-04", "2026-12-31", "2027-01-01"],
+  "short_days": ["2026-11-03", "2026-12-30"],
+  "working_weekends": ["2026-11-07"],
+  "deadlines": {"2026-10-21": "12:00"}
+}`
+
+func writeCalendar(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTest(t *testing.T) *Calendar {
+	t.Helper()
+	c, err := Load(writeCalendar(t, testJSON))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return c
+}
+
+func at(c *Calendar, s string) time.Time {
+	v, err := time.ParseInLocation("2006-01-02 15:04", s, c.loc)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestWorkingDaysAndDeadlines(t *testing.T) {
+	c := loadTest(t)
+
+	tests := []struct {
+		day          string
+		working      bool
+		short        bool
+		wantDeadline string // "" — нерабочий
+	}{
+		{"2026-10-19", true, false, "15:50"}, // обычный понедельник
+		{"2026-10-21", true, false, "12:00"}, // особое время на дату
+		{"2026-10-24", false, false, ""},     // суббота
+		{"2026-10-25", false, false, ""},     // воскресенье
+		{"2026-11-03", true, true, "14:50"},  // сокращённый предпраздничный
+		{"2026-11-04", false, false, ""},     // праздник в среду
+		{"2026-11-07", true, false, "15:50"}, // рабочая суббота (перенос)
+		{"2026-12-31", false, false, ""},     // праздник из файла
+	}
+	for _, tt := range tests {
+		noon := at(c, tt.day+" 12:00")
+		if got := c.IsWorkingDay(noon); got != tt.working {
+			t.Errorf("%s IsWorkingDay = %v, want %v", tt.day, got, tt.working)
+		}
+		if got := c.IsShortDay(noon); got != tt.short {
+			t.Errorf("%s IsShortDay = %v, want %v", tt.day, got, tt.short)
+		}
+		d, ok := c.Deadline(noon)
+		got := ""
+		if ok {
+			got = d.Format("15:04")
+		}
+		if got != tt.wantDeadline {
+			t.Errorf("%s Deadline = %q, want %q", tt.day, got, tt.wantDeadline)
+		}
+	}
+
+	// дата берётся по Москве: 23:30 UTC воскресенья — уже понедельник в Москве
+	if !c.IsWorkingDay(time.Date(2026, 10, 25, 23, 30, 0, 0, time.UTC)) {
+		t.Error("Sunday 23:30 UTC is Monday in Moscow")
+	}
+}
+
+func TestNextDeadlineAndWorkingDay(t *testing.T) {
+	c := loadTest(t)
+
+	tests := []struct {
+		after, nextDeadline, nextWorking string
+	}{
+		{"2026-10-19 10:00", "2026-10-19 15:50", "2026-10-20 00:00"},
+		{"2026-10-19 15:50", "2026-10-20 15:50", "2026-10-20 00:00"}, // строго после
+		{"2026-10-23 16:00", "2026-10-26 15:50", "2026-10-26 00:00"}, // пятница -> понедельник
+		{"2026-11-03 15:00", "2026-11-05 15:50", "2026-11-05 00:00"}, // через праздник
+		{"2026-11-06 16:00", "2026-11-07 15:50", "2026-11-07 00:00"}, // рабочая суббота
+		{"2026-12-30 15:00", "2027-01-04 15:50", "2027-01-04 00:00"}, // через Новый год и выходные
+	}
+	for _, tt := range tests {
+		after := at(c, tt.after)
+		if got := c.NextDeadline(after); !got.Equal(at(c, tt.nextDeadline)) {
+			t.Errorf("NextDeadline(%s) = %s, want %s", tt.after, got.Format("2006-01-02 15:04"), tt.nextDeadline)
+		}
+		if got := c.NextWorkingDay(after); !got.Equal(at(c, tt.nextWorking)) {
+			t.Errorf("NextWorkingDay(%s) = %s, want %s", tt.after, got.Format("2006-01-02 15:04"), tt.nextWorking)
+		}
+	}
+}
+
+func TestReloadKeepsDataOnError(t *testing.T) {
+	path := writeCalendar(t, testJSON)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, years := c.Info(); len(years) != 2 || years[0] != 2026 || years[1] != 2027 {
+		t.Errorf("years = %v", years)
+	}
+
+	for _, broken := range []string{
+		`{"deadline": "15:50"`,
+		`{"deadline": "25:00", "short_day_deadline": "14:50"}`,
+		`{"deadline": "15:50", "short_day_deadline": "14:50", "holidays": ["04.11.2026"]}`,
+	} {
+		if err := os.WriteFile(path, []byte(broken), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Reload(); err == nil {
+			t.Errorf("Reload(%s): want error", broken)
+		}
+		if c.IsWorkingDay(at(c, "2026-11-04 12:00")) {
+			t.Fatalf("after failed reload of %s the holiday is gone", broken)
+		}
+	}
+
+	// файла нет — Load всё равно отдаёт календарь пн-пт с дедлайном 15:50
+	c, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil {
+		t.Error("missing file: want error")
+	}
+	if d, ok := c.Deadline(at(c, "2026-11-04 12:00")); !ok || d.Format("15:04") != "15:50" {
+		t.Errorf("fallback calendar: %v %v", d, ok)
+	}
+}
@@ -0,0 +1,184 @@
+// Package companylookup ищет контрагента по ИНН: Rusprofile (разбор HTML), открытый поиск
+// ЕГРЮЛ ФНС и DaData. Провайдеры выбираются и выстраиваются в цепочку через конфиг
+// (COMPANY_LOOKUP=egrul,dadata,rusprofile): первый, кто нашёл организацию, и отвечает.
+//
+// Без сети: провайдер "fake" читает организации из JSON, а FixturesDir подменяет HTTP
+// у настоящих провайдеров записанными ответами (testdata/<провайдер>_<ИНН>.html|json) —
+// так весь путь разбора проверяется офлайн.
+package companylookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// имена провайдеров в COMPANY_LOOKUP
+const (
+	ProviderRusprofile = "rusprofile"
+	ProviderEGRUL      = "egrul"
+	ProviderDaData     = "dadata"
+	ProviderFake       = "fake"
+)
+
+// ErrNotFound — провайдер ответил, но организации с таким ИНН у него нет.
+var ErrNotFound = errors.New("организация не найдена")
+
+type Company struct {
+	INN     string
+	KPP     string
+	OGRN    string
+	Name    string // краткое наименование с ОПФ: ООО «Ромашка»
+	Address string
+	Source  string // имя провайдера, который нашёл
+}
+
+type CompanyLookup interface {
+	Name() string
+	Lookup(ctx context.Context, inn string) (*Company, error)
+}
+
+// Title — как провайдер называется в сообщениях пользователю и проверяющим.
+func Title(provider string) string {
+	switch provider {
+	case ProviderRusprofile:
+		return "Rusprofile"
+	case ProviderEGRUL:
+		return "ЕГРЮЛ ФНС"
+	case ProviderDaData:
+		return "DaData"
+	case ProviderFake:
+		return "тестовый справочник"
+	default:
+		return provider
+	}
+}
+
+// Chain опрашивает провайдеров по очереди до первого найденного.
+type Chain []CompanyLookup
+
+func (c Chain) Name() string {
+	names := make([]string, 0, len(c))
+	for _, l := range c {
+		names = append(names, l.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Lookup(ctx context.Context, inn string) (*Company, error) {
+	var errs []error
+	notFound := 0
+	for _, l := range c {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		co, err := l.Lookup(ctx, inn)
+		if err == nil {
+			return co, nil
+		}
+		if errors.Is(err, ErrNotFound) {
+			notFound++
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", Title(l.Name()), err))
+	}
+	if len(c) > 0 && notFound == len(c) {
+		return nil, ErrNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+type Options struct {
+	DaDataAPIKey string
+	FakePath     string        // JSON для провайдера fake
+	FixturesDir  string        // записанные ответы вместо HTTP (офлайн)
+	Timeout      time.Duration // на один HTTP-запрос, по умолчанию 12s
+}
+
+// New собирает цепочку из имён провайдеров (порядок = приоритет).
+func New(names []string, opt Options) (CompanyLookup, error) {
+	if len(names) == 0 {
+		names = []string{ProviderRusprofile}
+	}
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = 12 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var chain Chain
+	for _, name := range names {
+		var l CompanyLookup
+		switch name {
+		case ProviderRusprofile:
+			r := &Rusprofile{Client: client}
+			if opt.FixturesDir != "" {
+				r.Fetch = fixtureFetch(opt.FixturesDir, name, ".html")
+			}
+			l = r
+		case ProviderEGRUL:
+			e := &EGRUL{Client: client}
+			if opt.FixturesDir != "" {
+				e.Fetch = fixtureFetch(opt.FixturesDir, name, ".json")
+			}
+			l = e
+		case ProviderDaData:
+			if opt.DaDataAPIKey == "" && opt.FixturesDir == "" {
+				return nil, errors.New("dadata: не задан DADATA_API_KEY")
+			}
+			d := &DaData{APIKey: opt.DaDataAPIKey, Client: client}
+			if opt.FixturesDir != "" {
+				d.Fetch = fixtureFetch(opt.FixturesDir, name, ".json")
+			}
+			l = d
+		case ProviderFake:
+			f, err := LoadFake(opt.FakePath)
+			if err != nil {
+				return nil, err
+			}
+			l = f
+		default:
+			return nil, fmt.Errorf("неизвестный провайдер %q", name)
+		}
+		chain = append(chain, l)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+// fixtureFetch: ответ провайдера из файла dir/<provider>_<inn><ext>. Нет файла — ErrNotFound.
+func fixtureFetch(dir, provider, ext string) func(context.Context, string) ([]byte, error) {
+	return func(_ context.Context, inn string) ([]byte, error) {
+		raw, err := os.ReadFile(filepath.Join(dir, provider+"_"+inn+ext))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return raw, err
+	}
+}
+
+// do — GET/POST с общими заголовками; не-2xx считаем ошибкой.
+func do(client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tg-bot/1.0)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return body, nil
+}
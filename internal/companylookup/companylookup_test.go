@@ -0,0 +1,224 @@
+package companylookup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	sberINN    = "7707083893"
+	unknownINN = "7700000000" // в testdata/egrul_7700000000.json — пустой ответ ФНС
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func() (*Company, error)
+		want    Company
+		wantErr error // nil — ждём Company; ErrNotFound — именно его; иначе — любую ошибку
+		anyErr  bool
+	}{
+		{
+			name: "rusprofile card",
+			parse: func() (*Company, error) {
+				return ParseRusprofileHTML(string(readFixture(t, "rusprofile_7707083893.html")))
+			},
+			want: Company{KPP: "773601001", OGRN: "1027700132195", Name: "ПАО СБЕРБАНК", Address: "117312, г. Москва, ул. Вавилова, д. 19", Source: ProviderRusprofile},
+		},
+		{
+			name: "rusprofile page without card",
+			parse: func() (*Company, error) {
+				return ParseRusprofileHTML("<html><body>Ничего не найдено</body></html>")
+			},
+			anyErr: true,
+		},
+		{
+			name:  "egrul row",
+			parse: func() (*Company, error) { return ParseEGRULResult(readFixture(t, "egrul_7707083893.json"), sberINN) },
+			want:  Company{INN: sberINN, KPP: "773601001", OGRN: "1027700132195", Name: "ПАО СБЕРБАНК", Address: "117312, Г.МОСКВА, УЛ. ВАВИЛОВА, Д.19", Source: ProviderEGRUL},
+		},
+		{
+			name:    "egrul empty rows",
+			parse:   func() (*Company, error) { return ParseEGRULResult(readFixture(t, "egrul_7700000000.json"), unknownINN) },
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "egrul row for another INN",
+			parse:   func() (*Company, error) { return ParseEGRULResult(readFixture(t, "egrul_7707083893.json"), unknownINN) },
+			wantErr: ErrNotFound,
+		},
+		{
+			name:   "egrul broken JSON",
+			parse:  func() (*Company, error) { return ParseEGRULResult([]byte(`{"rows":`), sberINN) },
+			anyErr: true,
+		},
+		{
+			name:  "dadata party",
+			parse: func() (*Company, error) { return ParseDaDataParty(readFixture(t, "dadata_7707083893.json"), sberINN) },
+			want:  Company{INN: sberINN, KPP: "773601001", OGRN: "1027700132195", Name: "ПАО СБЕРБАНК", Address: "117312, г Москва, Академический р-н, ул Вавилова, д 19", Source: ProviderDaData},
+		},
+		{
+			name:    "dadata no suggestions",
+			parse:   func() (*Company, error) { return ParseDaDataParty([]byte(`{"suggestions":[]}`), sberINN) },
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, err := tt.parse()
+			switch {
+			case tt.anyErr:
+				if err == nil {
+					t.Fatalf("want error, got %+v", co)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if *co != tt.want {
+					t.Errorf("got  %+v\nwant %+v", *co, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// stubLookup — провайдер с заранее заданным ответом.
+type stubLookup struct {
+	name  string
+	co    *Company
+	err   error
+	calls int
+}
+
+func (s *stubLookup) Name() string { return s.name }
+
+func (s *stubLookup) Lookup(context.Context, string) (*Company, error) {
+	s.calls++
+	return s.co, s.err
+}
+
+func TestChainFallback(t *testing.T) {
+	found := &Company{INN: sberINN, Name: "ПАО СБЕРБАНК", Source: ProviderDaData}
+
+	t.Run("error then found", func(t *testing.T) {
+		down := &stubLookup{name: ProviderEGRUL, err: errors.New("HTTP 503")}
+		ok := &stubLookup{name: ProviderDaData, co: found}
+		co, err := Chain{down, ok}.Lookup(context.Background(), sberINN)
+		if err != nil || co != found {
+			t.Fatalf("Lookup = %+v, %v; want second provider's company", co, err)
+		}
+		if down.calls != 1 || ok.calls != 1 {
+			t.Errorf("calls = %d, %d; want 1, 1", down.calls, ok.calls)
+		}
+	})
+
+	t.Run("not found then found", func(t *testing.T) {
+		co, err := Chain{
+			&stubLookup{name: ProviderEGRUL, err: ErrNotFound},
+			&stubLookup{name: ProviderDaData, co: found},
+		}.Lookup(context.Background(), sberINN)
+		if err != nil || co != found {
+			t.Fatalf("Lookup = %+v, %v", co, err)
+		}
+	})
+
+	t.Run("first found stops the chain", func(t *testing.T) {
+		second := &stubLookup{name: ProviderDaData, co: found}
+		if _, err := (Chain{&stubLookup{name: ProviderEGRUL, co: found}, second}).Lookup(context.Background(), sberINN); err != nil {
+			t.Fatal(err)
+		}
+		if second.calls != 0 {
+			t.Errorf("second provider called %d times", second.calls)
+		}
+	})
+
+	t.Run("all not found", func(t *testing.T) {
+		_, err := Chain{
+			&stubLookup{name: ProviderEGRUL, err: ErrNotFound},
+			&stubLookup{name: ProviderDaData, err: ErrNotFound},
+		}.Lookup(context.Background(), sberINN)
+		if err != ErrNotFound {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("error and not found", func(t *testing.T) {
+		_, err := Chain{
+			&stubLookup{name: ProviderEGRUL, err: errors.New("HTTP 503")},
+			&stubLookup{name: ProviderDaData, err: ErrNotFound},
+		}.Lookup(context.Background(), sberINN)
+		if err == nil || err == ErrNotFound {
+			t.Fatalf("err = %v; want joined provider errors", err)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("joined error lost ErrNotFound: %v", err)
+		}
+	})
+}
+
+func TestNewWithFixtures(t *testing.T) {
+	l, err := New([]string{ProviderEGRUL, ProviderDaData, ProviderRusprofile}, Options{FixturesDir: "testdata"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := l.Name(); got != "egrul,dadata,rusprofile" {
+		t.Errorf("Name = %q", got)
+	}
+
+	co, err := l.Lookup(context.Background(), sberINN)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if co.Source != ProviderEGRUL || co.KPP != "773601001" {
+		t.Errorf("got %+v; want the EGRUL fixture", co)
+	}
+
+	// ЕГРЮЛ отвечает пустым списком, у DaData и Rusprofile файлов нет — везде «не найдено»
+	if _, err := l.Lookup(context.Background(), unknownINN); err != ErrNotFound {
+		t.Errorf("unknown INN: err = %v, want ErrNotFound", err)
+	}
+
+	// один провайдер — без цепочки, прямо из записанного HTML
+	rp, err := New([]string{ProviderRusprofile}, Options{FixturesDir: "testdata"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	co, err = rp.Lookup(context.Background(), sberINN)
+	if err != nil || co.INN != sberINN || co.Source != ProviderRusprofile {
+		t.Errorf("rusprofile fixture: %+v, %v", co, err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New([]string{ProviderDaData}, Options{}); err == nil {
+		t.Error("dadata without key and fixtures accepted")
+	}
+	if _, err := New([]string{"nope"}, Options{}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	f, err := New([]string{ProviderFake}, Options{FakePath: filepath.Join("testdata", "fake.json")})
+	if err != nil {
+		t.Fatalf("fake: %v", err)
+	}
+	if co, err := f.Lookup(context.Background(), "7736207543"); err != nil || co.Source != ProviderFake || co.INN != "7736207543" {
+		t.Errorf("fake lookup: %+v, %v", co, err)
+	}
+}
@@ -0,0 +1,100 @@
+package companylookup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DaData — «Организация по ИНН» (suggestions findById/party), нужен API-ключ.
+type DaData struct {
+	APIKey  string
+	BaseURL string // по умолчанию https://suggestions.dadata.ru
+	Client  *http.Client
+	// Fetch подменяет HTTP (записанные ответы); nil — настоящий запрос
+	Fetch func(ctx context.Context, inn string) ([]byte, error)
+}
+
+func (d *DaData) Name() string { return ProviderDaData }
+
+func (d *DaData) Lookup(ctx context.Context, inn string) (*Company, error) {
+	fetch := d.Fetch
+	if fetch == nil {
+		fetch = d.fetch
+	}
+	raw, err := fetch(ctx, inn)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDaDataParty(raw, inn)
+}
+
+func (d *DaData) fetch(ctx context.Context, inn string) ([]byte, error) {
+	base := d.BaseURL
+	if base == "" {
+		base = "https://suggestions.dadata.ru"
+	}
+	body, _ := json.Marshal(map[string]any{"query": strings.TrimSpace(inn), "branch_type": "MAIN"})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", base+"/suggestions/api/4_1/rs/findById/party", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Token "+d.APIKey)
+	return do(d.Client, req)
+}
+
+// ParseDaDataParty берёт первую подсказку с нужным ИНН.
+func ParseDaDataParty(raw []byte, inn string) (*Company, error) {
+	var res struct {
+		Suggestions []struct {
+			Value string `json:"value"`
+			Data  struct {
+				INN  string `json:"inn"`
+				KPP  string `json:"kpp"`
+				OGRN string `json:"ogrn"`
+				Name struct {
+					ShortWithOpf string `json:"short_with_opf"`
+					FullWithOpf  string `json:"full_with_opf"`
+				} `json:"name"`
+				Address struct {
+					Value             string `json:"value"`
+					UnrestrictedValue string `json:"unrestricted_value"`
+				} `json:"address"`
+			} `json:"data"`
+		} `json:"suggestions"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("разбор ответа: %w", err)
+	}
+
+	for _, s := range res.Suggestions {
+		if strings.TrimSpace(s.Data.INN) != inn {
+			continue
+		}
+		name := firstNonEmpty(s.Data.Name.ShortWithOpf, s.Value, s.Data.Name.FullWithOpf)
+		return &Company{
+			INN:     inn,
+			KPP:     strings.TrimSpace(s.Data.KPP),
+			OGRN:    strings.TrimSpace(s.Data.OGRN),
+			Name:    name,
+			Address: firstNonEmpty(s.Data.Address.UnrestrictedValue, s.Data.Address.Value),
+			Source:  ProviderDaData,
+		}, nil
+	}
+	return nil, ErrNotFound
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package companylookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EGRUL — открытый поиск ЕГРЮЛ/ЕГРИП на egrul.nalog.ru: POST запроса -> токен,
+// затем GET /search-result/<токен> (пока ФНС ищет, отвечает {"status":"wait"}).
+type EGRUL struct {
+	BaseURL string // по умолчанию https://egrul.nalog.ru
+	Client  *http.Client
+	// Fetch подменяет HTTP (записанные ответы search-result); nil — настоящий запрос
+	Fetch func(ctx context.Context, inn string) ([]byte, error)
+}
+
+func (e *EGRUL) Name() string { return ProviderEGRUL }
+
+func (e *EGRUL) Lookup(ctx context.Context, inn string) (*Company, error) {
+	fetch := e.Fetch
+	if fetch == nil {
+		fetch = e.fetch
+	}
+	raw, err := fetch(ctx, inn)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEGRULResult(raw, inn)
+}
+
+const egrulPolls = 5
+
+func (e *EGRUL) fetch(ctx context.Context, inn string) ([]byte, error) {
+	base := e.BaseURL
+	if base == "" {
+		base = "https://egrul.nalog.ru"
+	}
+
+	form := url.Values{"query": {strings.TrimSpace(inn)}}
+	req, err := http.NewRequestWithContext(ctx, "POST", base+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	raw, err := do(e.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tok struct {
+		T               string `json:"t"`
+		CaptchaRequired bool   `json:"captchaRequired"`
+	}
+	if err := json.Unmarshal(raw, &tok); err != nil {
+		return nil, fmt.Errorf("разбор ответа: %w", err)
+	}
+	if tok.CaptchaRequired {
+		return nil, fmt.Errorf("ФНС требует капчу")
+	}
+	if tok.T == "" {
+		return nil, fmt.Errorf("пустой токен поиска")
+	}
+
+	for i := 0; i < egrulPolls; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", base+"/search-result/"+url.PathEscape(tok.T), nil)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := do(e.Client, req)
+		if err != nil {
+			return nil, err
+		}
+		var st struct {
+			Status string `json:"status"`
+		}
+		_ = json.Unmarshal(raw, &st)
+		if st.Status != "wait" {
+			return raw, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return nil, fmt.Errorf("ФНС не ответила за %d попыток", egrulPolls)
+}
+
+// ParseEGRULResult разбирает ответ search-result: rows[] с полями
+// i — ИНН, p — КПП, o — ОГРН(ИП), c — краткое наименование, n — полное, a — адрес.
+func ParseEGRULResult(raw []byte, inn string) (*Company, error) {
+	var res struct {
+		Rows []struct {
+			I string `json:"i"`
+			P string `json:"p"`
+			O string `json:"o"`
+			C string `json:"c"`
+			N string `json:"n"`
+			A string `json:"a"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("разбор ответа: %w", err)
+	}
+
+	for _, r := range res.Rows {
+		if strings.TrimSpace(r.I) != inn {
+			continue
+		}
+		name := strings.TrimSpace(r.C)
+		if name == "" {
+			name = strings.TrimSpace(r.N)
+		}
+		return &Company{
+			INN:     inn,
+			KPP:     strings.TrimSpace(r.P),
+			OGRN:    strings.TrimSpace(r.O),
+			Name:    name,
+			Address: strings.TrimSpace(r.A),
+			Source:  ProviderEGRUL,
+		}, nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,41 @@
+package companylookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Fake — справочник из JSON для локального запуска без сети:
+// {"7707083893": {"KPP": "773601001", "Name": "ПАО Сбербанк", "Address": "..."}}.
+type Fake struct {
+	Companies map[string]Company
+}
+
+func LoadFake(path string) (*Fake, error) {
+	if path == "" {
+		return nil, fmt.Errorf("fake: не задан COMPANY_LOOKUP_FAKE_PATH")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fake: %w", err)
+	}
+	f := &Fake{}
+	if err := json.Unmarshal(raw, &f.Companies); err != nil {
+		return nil, fmt.Errorf("fake %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *Fake) Name() string { return ProviderFake }
+
+func (f *Fake) Lookup(_ context.Context, inn string) (*Company, error) {
+	co, ok := f.Companies[inn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	co.INN = inn
+	co.Source = ProviderFake
+	return &co, nil
+}
@@ -0,0 +1,95 @@
+package companylookup
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Rusprofile — поиск на rusprofile.ru. По ИНН сайт сразу отдаёт карточку организации,
+// данные берём из span#clip_kpp и из JS-объекта company: {...}.
+type Rusprofile struct {
+	BaseURL string // по умолчанию https://www.rusprofile.ru
+	Client  *http.Client
+	// Fetch подменяет HTTP (записанные ответы); nil — настоящий запрос
+	Fetch func(ctx context.Context, inn string) ([]byte, error)
+}
+
+func (r *Rusprofile) Name() string { return ProviderRusprofile }
+
+func (r *Rusprofile) Lookup(ctx context.Context, inn string) (*Company, error) {
+	fetch := r.Fetch
+	if fetch == nil {
+		fetch = r.fetch
+	}
+	raw, err := fetch(ctx, inn)
+	if err != nil {
+		return nil, err
+	}
+	co, err := ParseRusprofileHTML(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	co.INN = inn
+	return co, nil
+}
+
+func (r *Rusprofile) fetch(ctx context.Context, inn string) ([]byte, error) {
+	base := r.BaseURL
+	if base == "" {
+		base = "https://www.rusprofile.ru"
+	}
+	u := base + "/search?query=" + url.QueryEscape(strings.TrimSpace(inn))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(r.Client, req)
+}
+
+var (
+	reRusName = regexp.MustCompile(`company\s*:\s*\{[\s\S]*?name\s*:\s*'([^']*)'`)
+	reRusAddr = regexp.MustCompile(`company\s*:\s*\{[\s\S]*?address\s*:\s*'([^']*)'`)
+	reRusOGRN = regexp.MustCompile(`company\s*:\s*\{[\s\S]*?ogrn\s*:\s*'([^']*)'`)
+)
+
+// ParseRusprofileHTML разбирает карточку организации. Ни одного поля — значит,
+// поиск ничего не нашёл или сайт поменял разметку.
+func ParseRusprofileHTML(htmlText string) (*Company, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlText))
+	if err != nil {
+		return nil, err
+	}
+
+	kpp := strings.TrimSpace(doc.Find("span#clip_kpp").First().Text())
+
+	var name, address, ogrn string
+	if m := reRusName.FindStringSubmatch(htmlText); len(m) > 1 {
+		name = html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	if m := reRusAddr.FindStringSubmatch(htmlText); len(m) > 1 {
+		address = html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	if m := reRusOGRN.FindStringSubmatch(htmlText); len(m) > 1 {
+		ogrn = strings.TrimSpace(m[1])
+	}
+
+	if kpp == "" && name == "" && address == "" {
+		return nil, errors.New("данные не найдены (возможно изменился HTML)")
+	}
+
+	return &Company{
+		KPP:     kpp,
+		OGRN:    ogrn,
+		Name:    name,
+		Address: address,
+		Source:  ProviderRusprofile,
+	}, nil
+}
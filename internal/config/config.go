@@ -5,6 +5,14 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// режимы BOT3_INTAKE_MODE
+const (
+	IntakeQueue  = "queue"  // принять, но оформить следующим рабочим днём
+	IntakeReject = "reject" // не принимать до открытия приёма
+	IntakeOff    = "off"    // принимать всегда, только предупреждать
 )
 
 type Config struct {
@@ -33,9 +41,44 @@ type Config struct {
 	Bot3ApprovalChatID  int64
 	// путь к xlsx-шаблону счёта (используется в bot3)
 	Bot3InvoiceTemplatePath string
+	// сколько хранить незавершённый черновик заявки
+	Bot3DraftTTL time.Duration
+	// окно приёма заявок: с Bot3IntakeOpen (от полуночи) до окончания приёма по производственному календарю
+	Bot3IntakeOpen time.Duration
+	// что делать с заявкой вне окна: IntakeQueue, IntakeReject или IntakeOff
+	Bot3IntakeMode string
 
 	SofficePath string
 
+	// поиск контрагента по ИНН: провайдеры по приоритету ("egrul,dadata,rusprofile"), пусто = rusprofile
+	CompanyLookup         []string
+	DaDataAPIKey          string
+	CompanyLookupFakePath string // JSON для провайдера fake
+	// каталог с записанными ответами провайдеров — поиск без сети (internal/companylookup/testdata)
+	CompanyLookupFixtures string
+	// сколько данные контрагента в кэше считаются свежими
+	CounterpartyTTL time.Duration
+
+	// производственный календарь (праздники, сокращённые дни, время окончания приёма заявок)
+	ProductionCalendarPath string
+
+	// сколько апдейтов одного бота обрабатываются одновременно (разные чаты)
+	UpdateWorkers int
+
+	// сколько ждать завершения начатых обработчиков при остановке, потом они отменяются
+	ShutdownTimeout time.Duration
+
+	// webhook вместо long polling. Пустой WebhookListenAddr = polling.
+	// Без WebhookPublicURL вебхук в телеграме не регистрируется (удобно для локальной проверки curl'ом).
+	// WebhookSecretToken обязателен: без него webhook-режим не стартует.
+	WebhookListenAddr  string // ":8443"
+	WebhookPublicURL   string // "https://bots.example.com" — к нему добавляется WebhookPath/<bot>
+	WebhookPath        string // префикс пути, по умолчанию "/tg"
+	WebhookSecretToken string // сверяется с заголовком X-Telegram-Bot-Api-Secret-Token
+
+	// botd: какие боты запускать в одном процессе ("bot1,bot3"); пусто = все, у кого задан токен
+	BotdBots []string
+
 	ResponderIDs     map[int64]bool
 	ResponderAliases map[int64]string
 }
@@ -75,10 +118,70 @@ func MustLoad() *Config {
 	}
 	cfg.Bot3InvoiceTemplatePath = tpl
 
+	// ✅ срок жизни брошенного черновика заявки (часы)
+	ttlHours := mustInt64("BOT3_DRAFT_TTL_HOURS")
+	if ttlHours <= 0 {
+		ttlHours = 72
+	}
+	cfg.Bot3DraftTTL = time.Duration(ttlHours) * time.Hour
+
+	// ✅ окно приёма заявок bot3
+	cfg.Bot3IntakeOpen = mustClock("BOT3_INTAKE_OPEN")
+	cfg.Bot3IntakeMode = strings.ToLower(strings.TrimSpace(os.Getenv("BOT3_INTAKE_MODE")))
+	switch cfg.Bot3IntakeMode {
+	case "":
+		cfg.Bot3IntakeMode = IntakeQueue
+	case IntakeQueue, IntakeReject, IntakeOff:
+	default:
+		log.Fatalf("bad BOT3_INTAKE_MODE: %q (want %s, %s or %s)", cfg.Bot3IntakeMode, IntakeQueue, IntakeReject, IntakeOff)
+	}
+
 	cfg.ResponderIDs = parseIDs(os.Getenv("RESPONDER_IDS"))
 	cfg.ResponderAliases = parseAliases(os.Getenv("RESPONDER_ALIASES"))
 
 	cfg.SofficePath = strings.TrimSpace(os.Getenv("SOFFICE_PATH"))
+
+	cfg.CompanyLookup = parseNames(os.Getenv("COMPANY_LOOKUP"))
+	cfg.DaDataAPIKey = strings.TrimSpace(os.Getenv("DADATA_API_KEY"))
+	cfg.CompanyLookupFakePath = strings.TrimSpace(os.Getenv("COMPANY_LOOKUP_FAKE_PATH"))
+	cfg.CompanyLookupFixtures = strings.TrimSpace(os.Getenv("COMPANY_LOOKUP_FIXTURES"))
+
+	// ✅ кэш контрагентов (часы), по умолчанию 30 дней
+	cpHours := mustInt64("COUNTERPARTY_TTL_HOURS")
+	if cpHours <= 0 {
+		cpHours = 30 * 24
+	}
+	cfg.CounterpartyTTL = time.Duration(cpHours) * time.Hour
+
+	cfg.ProductionCalendarPath = strings.TrimSpace(os.Getenv("PRODUCTION_CALENDAR_PATH"))
+	if cfg.ProductionCalendarPath == "" {
+		cfg.ProductionCalendarPath = "assets/production_calendar.json"
+	}
+
+	cfg.UpdateWorkers = int(mustInt64("UPDATE_WORKERS"))
+	if cfg.UpdateWorkers <= 0 {
+		cfg.UpdateWorkers = 8
+	}
+
+	shutdownSec := mustInt64("SHUTDOWN_TIMEOUT_SEC")
+	if shutdownSec <= 0 {
+		shutdownSec = 30
+	}
+	cfg.ShutdownTimeout = time.Duration(shutdownSec) * time.Second
+
+	cfg.BotdBots = parseNames(os.Getenv("BOTD_BOTS"))
+
+	cfg.WebhookListenAddr = strings.TrimSpace(os.Getenv("WEBHOOK_LISTEN_ADDR"))
+	cfg.WebhookPublicURL = strings.TrimRight(strings.TrimSpace(os.Getenv("WEBHOOK_PUBLIC_URL")), "/")
+	cfg.WebhookPath = "/" + strings.Trim(strings.TrimSpace(os.Getenv("WEBHOOK_PATH")), "/")
+	if cfg.WebhookPath == "/" {
+		cfg.WebhookPath = "/tg"
+	}
+	cfg.WebhookSecretToken = strings.TrimSpace(os.Getenv("WEBHOOK_SECRET_TOKEN"))
+	// ✅ без secret token вебхук принимал бы апдейты от кого угодно, кто узнал URL
+	if cfg.WebhookListenAddr != "" && cfg.WebhookSecretToken == "" {
+		log.Fatalf("WEBHOOK_LISTEN_ADDR is set but WEBHOOK_SECRET_TOKEN is empty")
+	}
 	return cfg
 }
 
@@ -94,6 +197,19 @@ func mustInt64(key string) int64 {
 	return n
 }
 
+// mustClock: "09:00" -> 9h от полуночи, пусто -> 0.
+func mustClock(key string) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		log.Fatalf("bad %s: want HH:MM, got %q", key, v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
 func parseIDs(s string) map[int64]bool {
 	out := map[int64]bool{}
 	s = strings.TrimSpace(s)
@@ -115,6 +231,18 @@ func parseIDs(s string) map[int64]bool {
 	return out
 }
 
+func parseNames(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
 func parseAliases(s string) map[int64]string {
 	out := map[int64]string{}
 	s = strings.TrimSpace(s)
@@ -0,0 +1,170 @@
+// Package cron разбирает расписания в формате crontab из 5 полей:
+// минута час день-месяца месяц день-недели.
+//
+// Поддерживаются "*", числа, списки "1,15", диапазоны "1-5" и шаги "*/10", "8-18/2".
+// День недели: 0-7, где 0 и 7 — воскресенье. Если заданы и день месяца, и день недели,
+// срабатывает по любому из них (как в обычном cron).
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	domAny bool // "*" в дне месяца
+	dowAny bool // "*" в дне недели
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse разбирает выражение из 5 полей.
+func Parse(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields, got %d", spec, len(parts))
+	}
+
+	var masks [5]uint64
+	for i, p := range parts {
+		m, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+		masks[i] = m
+	}
+
+	// 7 — тоже воскресенье
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseNum(a, f); err != nil {
+				return 0, err
+			}
+			if hi, err = parseNum(b, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: bad range %q", f.name, rng)
+			}
+		default:
+			n, err := parseNum(rng, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			hi = n
+			if hasStep {
+				hi = f.max // "5/15" = "5-59/15"
+			}
+		}
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: bad step %q", f.name, stepStr)
+			}
+			step = n
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseNum(s string, f field) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %q out of range %d-%d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Matches: подходит ли минута t под расписание (в часовом поясе t).
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.minute&(1<<uint(t.Minute())) != 0 &&
+		s.hour&(1<<uint(t.Hour())) != 0 &&
+		s.dayMatches(t)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domAny && !s.dowAny {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// Next — первая подходящая минута строго после after (в часовом поясе after).
+// Zero time, если за 5 лет совпадений нет (например, "0 0 31 2 *").
+func (s *Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+
+	for t.Before(end) {
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
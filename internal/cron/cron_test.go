@@ -0,0 +1,97 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): want error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, msk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		spec, after, want string
+	}{
+		// ежедневно: строго после after, секунды отбрасываются
+		{"35 15 * * *", "2026-10-19 10:00", "2026-10-19 15:35"},
+		{"35 15 * * *", "2026-10-19 15:35", "2026-10-20 15:35"},
+		{"35 15 * * *", "2026-10-19 23:59", "2026-10-20 15:35"},
+		// будни: пятница вечером -> понедельник
+		{"0 9 * * 1-5", "2026-10-23 10:00", "2026-10-26 09:00"},
+		// выходные, воскресенье как 7
+		{"0 12 * * 6,7", "2026-10-19 00:00", "2026-10-24 12:00"},
+		{"0 12 * * 7", "2026-10-24 13:00", "2026-10-25 12:00"},
+		// шаги и списки
+		{"*/15 * * * *", "2026-10-19 10:07", "2026-10-19 10:15"},
+		{"0 8-18/2 * * *", "2026-10-19 09:00", "2026-10-19 10:00"},
+		{"5/20 * * * *", "2026-10-19 10:26", "2026-10-19 10:45"},
+		{"0 10 1,15 * *", "2026-10-02 00:00", "2026-10-15 10:00"},
+		// день месяца: через конец года и короткий месяц
+		{"0 10 1 * *", "2026-12-05 00:00", "2027-01-01 10:00"},
+		{"0 0 31 * *", "2026-11-01 00:00", "2026-12-31 00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		// и день месяца, и день недели — срабатывает по любому (13-е или пятница)
+		{"0 9 13 * 5", "2026-10-19 00:00", "2026-10-23 09:00"},
+		{"0 9 13 * 5", "2026-11-10 00:00", "2026-11-13 09:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := s.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.spec, tt.after, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+
+	// 31 февраля не бывает — zero time, а не бесконечный цикл
+	s, _ := Parse("0 0 31 2 *")
+	if got := s.Next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("31 Feb: got %s, want zero", got)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	s, err := Parse("30 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mon := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+	if !s.Matches(mon) {
+		t.Error("monday 09:30 should match")
+	}
+	if s.Matches(mon.Add(time.Minute)) {
+		t.Error("09:31 should not match")
+	}
+	if s.Matches(mon.AddDate(0, 0, 5)) {
+		t.Error("saturday should not match")
+	}
+}
@@ -0,0 +1,377 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// SendApplicationToApproval — счёт в чат подтверждения и запись заявки в БД.
+// Ошибку пользователю не пишет: текст ошибки показывает вызывающий, черновик при этом остаётся.
+func (b *Bot) SendApplicationToApproval(
+	ctx context.Context,
+	userChatID int64,
+	userMessageID int,
+	text string,
+	draft applicationDraft,
+) error {
+	if b.profile.ApprovalChatID == 0 {
+		// если нет чата подтверждения — просто сообщим пользователю
+		_, _ = b.send(tgbotapi.NewMessage(userChatID, "Заявка принята. (чат подтверждения не настроен)"))
+		return nil
+	}
+
+	// 0) исправление отклонённой заявки: «забираем» её, чтобы не отправить исправление дважды
+	var parent *storage.Application
+	sent := false
+	if draft.ResubmitOf != 0 {
+		p, ok, err := storage.GetApplication(b.db, draft.ResubmitOf)
+		if err != nil {
+			return fmt.Errorf("ошибка БД: %w", err)
+		}
+		claimed := false
+		if ok {
+			claimed, err = storage.SetApplicationStatus(b.db, p.ID, storage.AppStatusResubmitted, storage.AppStatusRejected)
+			if err != nil {
+				b.logf("SetApplicationStatus error: %v", err)
+			}
+		}
+		if !claimed {
+			return errors.New("эта заявка уже исправлена и отправлена повторно")
+		}
+		parent = p
+		defer func() {
+			if !sent {
+				// не дошло до чата подтверждения — исправление можно будет отправить ещё раз
+				_, _ = storage.SetApplicationStatus(b.db, parent.ID, storage.AppStatusRejected, storage.AppStatusResubmitted)
+			}
+		}()
+	}
+
+	// 1) номер счёта (уникальный); у исправления — номер исходной заявки
+	var invoiceNo int64
+	if parent != nil {
+		invoiceNo = parent.InvoiceNo
+	} else {
+		no, err := storage.NextInvoiceNumber(b.db)
+		if err != nil {
+			return fmt.Errorf("не смог сформировать счёт: %w", err)
+		}
+		invoiceNo = no
+	}
+
+	// 2) дата (заявка вне окна приёма — датой следующего рабочего дня)
+	now := time.Now().In(moscowLocation())
+	if draft.QueuedFor != 0 {
+		now = time.Unix(draft.QueuedFor, 0).In(moscowLocation())
+	}
+
+	// 3) шаблон
+	tpl := strings.TrimSpace(b.cfg.Bot3InvoiceTemplatePath)
+	if tpl == "" {
+		tpl = "assets/invoice_template.xlsx"
+	}
+
+	// 4) генерим xlsx
+	tempDir, _ := os.MkdirTemp("", "tg3-invoice-*")
+
+	xlsxPath, perr := FillInvoiceTemplateXLSX(tpl, tempDir, invoiceNo, now, draft, draft.Items)
+	if perr != nil {
+		_ = os.RemoveAll(tempDir)
+		return fmt.Errorf("не смог сформировать счёт: %w", perr)
+	}
+
+	pdfPath := ""
+	if xlsxPath != "" {
+		if p, err := ConvertXLSXToPDFLibreOffice(ctx, b.cfg, xlsxPath, tempDir); err != nil {
+			// не роняем процесс целиком: просто логика с предупреждением в approval чат
+			_, _ = b.send(tgbotapi.NewMessage(b.profile.ApprovalChatID, "⚠️ Не смог сконвертировать XLSX→PDF: "+err.Error()))
+		} else {
+			pdfPath = p
+		}
+	}
+
+	kb := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", "app_ok"),
+			tgbotapi.NewInlineKeyboardButtonData("✍️ Правка", "app_fix"),
+		),
+	)
+
+	// 5) в approval отправляем ФАЙЛ (не текст)
+	doc := tgbotapi.NewDocument(b.profile.ApprovalChatID, tgbotapi.FilePath(xlsxPath))
+	doc.Caption = fmt.Sprintf("Счёт № %d (xlsx)\n\n%s", invoiceNo, text)
+	doc.ReplyMarkup = kb
+	if parent != nil && parent.ApprovalChatID == b.profile.ApprovalChatID {
+		// ✅ исправление — ответом на исходную заявку, чтобы было видно всю историю
+		doc.ReplyToMessageID = parent.ApprovalMessageID
+		doc.AllowSendingWithoutReply = true
+	}
+
+	approvalMsg, sendErr := b.send(doc)
+	if sendErr != nil {
+		// ВАЖНО: показываем ошибку прямо в approval-чате
+		_, _ = b.send(tgbotapi.NewMessage(b.profile.ApprovalChatID, "❌ Не смог отправить XLSX в этот чат: "+sendErr.Error()))
+		_ = os.RemoveAll(tempDir)
+		return errors.New("не смог отправить счёт на подтверждение")
+	}
+
+	// 6) заявка в БД сразу после отправки: кнопки approval-сообщения без строки в БД никуда не ведут
+	draftJSON, _ := json.Marshal(draft)
+	app := &storage.Application{
+		InvoiceNo:         invoiceNo,
+		InvoiceDate:       now.Unix(),
+		UserChatID:        userChatID,
+		UserMessageID:     userMessageID,
+		Text:              text,
+		DraftJSON:         string(draftJSON),
+		Status:            storage.AppStatusPending,
+		ApprovalChatID:    b.profile.ApprovalChatID,
+		ApprovalMessageID: approvalMsg.MessageID,
+		XlsxPath:          xlsxPath,
+		PdfPath:           pdfPath,
+		TempDir:           tempDir,
+	}
+	if parent != nil {
+		app.ParentID = parent.ID
+	}
+	if _, err := storage.CreateApplication(b.db, app); err != nil {
+		b.logf("CreateApplication error: %v", err)
+		// убираем «висящий» счёт из чата подтверждения; не удалилось — хотя бы снимаем кнопки и помечаем
+		if _, derr := b.bot.Request(tgbotapi.NewDeleteMessage(b.profile.ApprovalChatID, approvalMsg.MessageID)); derr != nil {
+			b.logf("delete approval message error: %v", derr)
+			_, _ = b.send(tgbotapi.NewEditMessageCaption(b.profile.ApprovalChatID, approvalMsg.MessageID,
+				fmt.Sprintf("❌ Счёт № %d не сохранён в БД — заявка не принята, пользователь отправит её заново.", invoiceNo)))
+		}
+		_ = os.RemoveAll(tempDir)
+		return fmt.Errorf("не смог сохранить заявку (ошибка БД): %w", err)
+	}
+	sent = true
+
+	if pdfPath != "" {
+		pdfDoc := tgbotapi.NewDocument(b.profile.ApprovalChatID, tgbotapi.FilePath(pdfPath))
+		pdfDoc.Caption = fmt.Sprintf("Счёт № %d (pdf)", invoiceNo)
+		pdfDoc.ReplyToMessageID = approvalMsg.MessageID
+		_, _ = b.send(pdfDoc)
+	}
+
+	// как в старом варианте — маппинг reply цепочек
+	_ = storage.AddMap(b.db, b.profile.ApprovalChatID, approvalMsg.MessageID, userChatID, userMessageID)
+
+	// 7) дополнительно — навигатору тоже ФАЙЛ (если задан)
+	if b.profile.NavigatorChatID != 0 {
+		navDoc := tgbotapi.NewDocument(b.profile.NavigatorChatID, tgbotapi.FilePath(xlsxPath))
+		navDoc.Caption = fmt.Sprintf("Счёт № %d (xlsx)\n\n%s", invoiceNo, text)
+		if _, err := b.send(navDoc); err != nil {
+			// не критично, но пусть будет видно
+			_, _ = b.send(tgbotapi.NewMessage(b.profile.ApprovalChatID, "⚠️ Не смог отправить XLSX навигатору: "+err.Error()))
+		}
+	}
+
+	if b.profile.NavigatorChatID != 0 && pdfPath != "" {
+		navPdf := tgbotapi.NewDocument(b.profile.NavigatorChatID, tgbotapi.FilePath(pdfPath))
+		navPdf.Caption = fmt.Sprintf("Счёт № %d (pdf)", invoiceNo)
+		_, _ = b.send(navPdf)
+	}
+	return nil
+}
+
+func (b *Bot) HandleApprovalCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	if cq == nil || cq.Message == nil || cq.Message.Chat == nil {
+		return
+	}
+	if cq.Message.Chat.ID != b.profile.ApprovalChatID {
+		return
+	}
+
+	_, _ = b.bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+
+	approvalMsgID := cq.Message.MessageID
+
+	app, ok, err := storage.GetApplicationByApprovalMessage(b.db, b.profile.ApprovalChatID, approvalMsgID)
+	if err != nil {
+		b.logf("GetApplicationByApprovalMessage error: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	switch cq.Data {
+	case "app_ok":
+		// сначала «захватываем» заявку, чтобы двойной клик не отправил счёт дважды
+		claimed, err := storage.SetApplicationStatus(b.db, app.ID, storage.AppStatusApproved, storage.AppStatusPending, storage.AppStatusAwaitFix)
+		if err != nil {
+			b.logf("SetApplicationStatus error: %v", err)
+			return
+		}
+		if !claimed {
+			return
+		}
+
+		if err := b.sendInvoiceToUser(ctx, app); err != nil {
+			// возвращаем заявку в прежний статус — можно будет нажать ещё раз
+			_, _ = storage.SetApplicationStatus(b.db, app.ID, app.Status)
+
+			fail := tgbotapi.NewMessage(b.profile.ApprovalChatID, "❌ Не смог отправить счёт пользователю: "+err.Error())
+			fail.ReplyToMessageID = approvalMsgID
+			_, _ = b.send(fail)
+			return
+		}
+
+		ack := tgbotapi.NewMessage(b.profile.ApprovalChatID, "✅ Счёт отправлен пользователю.")
+		ack.ReplyToMessageID = approvalMsgID
+		_, _ = b.send(ack)
+
+		cleanupApprovalFiles(app)
+
+	case "app_fix":
+		ok, err := storage.SetApplicationStatus(b.db, app.ID, storage.AppStatusAwaitFix, storage.AppStatusPending)
+		if err != nil {
+			b.logf("SetApplicationStatus error: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+
+		ack := tgbotapi.NewMessage(b.profile.ApprovalChatID, "✍️ Ок. Напишите причину правок reply на это сообщение.")
+		ack.ReplyToMessageID = approvalMsgID
+		_, _ = b.send(ack)
+	}
+}
+
+func (b *Bot) HandleApprovalGroupMessage(m *tgbotapi.Message) {
+	if m == nil || m.Chat == nil {
+		return
+	}
+	if m.Chat.ID != b.profile.ApprovalChatID {
+		return
+	}
+	if m.ReplyToMessage == nil {
+		return
+	}
+
+	targetID := m.ReplyToMessage.MessageID
+
+	app, ok, err := storage.GetApplicationByApprovalMessage(b.db, b.profile.ApprovalChatID, targetID)
+	if err != nil {
+		b.logf("GetApplicationByApprovalMessage error: %v", err)
+		return
+	}
+	if !ok || app.Status != storage.AppStatusAwaitFix {
+		return
+	}
+
+	reason := strings.TrimSpace(m.Text)
+	if reason == "" {
+		return
+	}
+
+	claimed, err := storage.RejectApplication(b.db, app.ID, reason)
+	if err != nil {
+		b.logf("RejectApplication error: %v", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	// ✅ черновик заявки остаётся в БД — пользователь исправляет только нужное
+	out := tgbotapi.NewMessage(app.UserChatID, fmt.Sprintf(
+		"Заявка (счёт № %d) не подтверждена. Причина:\n%s\n\nНажмите «✏️ Исправить заявку», чтобы поправить нужные поля и отправить её заново.",
+		app.InvoiceNo, reason,
+	))
+	out.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Исправить заявку", fmt.Sprintf("appfix:%d", app.ID)),
+		),
+	)
+	_, _ = b.send(out)
+
+	ack := tgbotapi.NewMessage(b.profile.ApprovalChatID, "📨 Причина отправлена пользователю.")
+	ack.ReplyToMessageID = targetID
+	_, _ = b.send(ack)
+
+	cleanupApprovalFiles(app)
+}
+
+// sendInvoiceToUser отправляет пользователю файл счёта (приоритет PDF).
+// Если после рестарта/деплоя временных файлов уже нет — пересобираем счёт из черновика в БД.
+func (b *Bot) sendInvoiceToUser(ctx context.Context, app *storage.Application) error {
+	if !fileExists(app.PdfPath) && !fileExists(app.XlsxPath) {
+		if err := b.rebuildApplicationFiles(ctx, app); err != nil {
+			return err
+		}
+	}
+
+	var doc tgbotapi.DocumentConfig
+	if fileExists(app.PdfPath) {
+		doc = tgbotapi.NewDocument(app.UserChatID, tgbotapi.FilePath(app.PdfPath))
+		doc.Caption = "Счёт на оплату № " + strconv.FormatInt(app.InvoiceNo, 10)
+	} else {
+		doc = tgbotapi.NewDocument(app.UserChatID, tgbotapi.FilePath(app.XlsxPath))
+		doc.Caption = "Счёт на оплату № " + strconv.FormatInt(app.InvoiceNo, 10) + " (xlsx)"
+	}
+	_, err := b.send(doc)
+	return err
+}
+
+func (b *Bot) rebuildApplicationFiles(ctx context.Context, app *storage.Application) error {
+	var draft applicationDraft
+	if err := json.Unmarshal([]byte(app.DraftJSON), &draft); err != nil {
+		return fmt.Errorf("не найден файл счёта, черновик повреждён: %w", err)
+	}
+
+	tpl := strings.TrimSpace(b.cfg.Bot3InvoiceTemplatePath)
+	if tpl == "" {
+		tpl = "assets/invoice_template.xlsx"
+	}
+
+	tempDir, err := os.MkdirTemp("", "tg3-invoice-*")
+	if err != nil {
+		return err
+	}
+
+	invoiceDate := time.Unix(app.InvoiceDate, 0).In(moscowLocation())
+	xlsxPath, err := FillInvoiceTemplateXLSX(tpl, tempDir, app.InvoiceNo, invoiceDate, draft, draft.Items)
+	if err != nil {
+		_ = os.RemoveAll(tempDir)
+		return err
+	}
+
+	pdfPath, err := ConvertXLSXToPDFLibreOffice(ctx, b.cfg, xlsxPath, tempDir)
+	if err != nil {
+		b.logf("rebuild invoice %d: pdf convert error: %v", app.InvoiceNo, err)
+		pdfPath = ""
+	}
+
+	app.XlsxPath = xlsxPath
+	app.PdfPath = pdfPath
+	app.TempDir = tempDir
+	return nil
+}
+
+func cleanupApprovalFiles(it *storage.Application) {
+	if it == nil {
+		return
+	}
+	if it.XlsxPath != "" {
+		_ = os.Remove(it.XlsxPath)
+	}
+	if it.PdfPath != "" {
+		_ = os.Remove(it.PdfPath)
+	}
+	if it.TempDir != "" {
+		_ = os.RemoveAll(it.TempDir)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// сколько покупателей из адресной книги показываем кнопками
+const savedBuyersLimit = 6
+
+// sendSavedBuyers — кнопки с покупателями, по которым пользователь уже отправлял заявки.
+func (b *Bot) sendSavedBuyers(chatID, userID int64) {
+	list, err := storage.ListSavedBuyers(b.db, userID, savedBuyersLimit)
+	if err != nil {
+		b.logf("ListSavedBuyers error: %v", err)
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sb := range list {
+		title := sb.LegalName
+		if title == "" {
+			title = "ИНН " + sb.INN
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s · %s", title, sb.INN), "appd:buyer:"+sb.INN),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, "Или выберите покупателя из прошлых заявок:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+// pickSavedBuyer — покупатель выбран кнопкой: ИНН, КПП, название и договор берём из адресной книги.
+func (b *Bot) pickSavedBuyer(ctx context.Context, chatID, userID int64, st *userAppState, inn string) {
+	sb, ok, err := storage.GetSavedBuyer(b.db, userID, inn)
+	if err != nil {
+		b.logf("GetSavedBuyer error: %v", err)
+	}
+	if !ok {
+		b.promptForStage(chatID, st)
+		return
+	}
+
+	_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Покупатель: %s, ИНН %s ✅", nz(sb.LegalName), sb.INN)))
+	b.applyINN(ctx, chatID, st, sb.INN, sb)
+}
+
+// applyINN — ИНН проверен: данные из кэша/реестра (lookupCounterparty сам решает, свежий ли кэш),
+// дальше КПП или название. saved != nil — покупатель из адресной книги, его данными закрываем пропуски.
+func (b *Bot) applyINN(ctx context.Context, chatID int64, st *userAppState, inn string, saved *storage.SavedBuyer) {
+	st.Draft.INN = inn
+	st.Draft.RusKPP, st.Draft.RusName, st.Draft.RusAddress, st.Draft.RusErr = "", "", "", ""
+	st.Draft.KPP = ""
+	st.Draft.SavedContract = ""
+
+	st.Draft.LookupSource = ""
+
+	co, err := b.lookupCounterparty(ctx, inn, false)
+	if err != nil {
+		st.Draft.RusErr = "поиск по ИНН: " + err.Error()
+	} else {
+		st.Draft.RusKPP = co.KPP
+		st.Draft.RusName = co.Name
+		st.Draft.RusAddress = co.Address
+		st.Draft.LookupSource = co.Source
+	}
+	if saved != nil {
+		st.Draft.SavedContract = saved.Contract
+	}
+
+	switch {
+	case isSoleProprietorINN(inn):
+		// у ИП КПП нет, даже если источник что-то вернул
+		st.Draft.RusKPP = ""
+		st.Stage = stageAwaitLegalName
+	case st.Draft.RusKPP != "":
+		if _, kerr := normalizeKPP(st.Draft.RusKPP); kerr != nil {
+			b.logf("lookup %s: bad KPP %q from %s", inn, st.Draft.RusKPP, st.Draft.LookupSource)
+			st.Draft.RusKPP = ""
+			st.Stage = stageAwaitKPP
+		} else {
+			st.Stage = stageAwaitLegalName
+		}
+	case saved != nil && saved.KPP != "":
+		st.Draft.KPP = saved.KPP
+		st.Stage = stageAwaitLegalName
+	default:
+		st.Stage = stageAwaitKPP
+	}
+
+	// ✅ название из адресной книги уже сверяли с реестром — не спрашиваем, если реестр не поменял его
+	if st.Stage == stageAwaitLegalName && saved != nil && saved.LegalName != "" &&
+		(st.Draft.RusName == "" || orgNamesMatch(saved.LegalName, st.Draft.RusName)) {
+		st.Draft.LegalName = saved.LegalName
+		b.afterLegalName(chatID, st)
+		return
+	}
+	b.promptForStage(chatID, st)
+}
+
+// rememberBuyer — после отправки заявки кладём покупателя в адресную книгу пользователя.
+func (b *Bot) rememberBuyer(userID int64, d *applicationDraft) {
+	if strings.TrimSpace(d.INN) == "" {
+		return
+	}
+	contract := strings.TrimSpace(d.Contract)
+	if contract == "0" {
+		contract = ""
+	}
+	if err := storage.SaveBuyer(b.db, &storage.SavedBuyer{
+		UserID:    userID,
+		INN:       d.INN,
+		LegalName: strings.TrimSpace(d.LegalName),
+		KPP:       d.KPP,
+		Contract:  contract,
+		UsedAt:    time.Now(),
+	}); err != nil {
+		b.logf("SaveBuyer error: %v", err)
+	}
+}
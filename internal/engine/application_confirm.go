@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/config"
+)
+
+// goConfirm — итоговая сводка и предпросмотр счёта перед отправкой на подтверждение.
+func (b *Bot) goConfirm(ctx context.Context, chatID int64, st *userAppState) {
+	st.Reviewing = true
+	st.Stage = stageConfirm
+	st.EditField = ""
+
+	msg := tgbotapi.NewMessage(chatID, "Почти готово — проверьте счёт.")
+	msg.ReplyMarkup = stepControlKeyboard()
+	_, _ = b.send(msg)
+
+	b.sendInvoicePreview(ctx, chatID, st)
+	b.sendConfirmSummary(chatID, st)
+}
+
+func (b *Bot) sendConfirmSummary(chatID int64, st *userAppState) {
+	msg := tgbotapi.NewMessage(chatID, b.confirmText(&st.Draft, time.Now()))
+	msg.ReplyMarkup = confirmKeyboard()
+	_, _ = b.send(msg)
+}
+
+func confirmKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📤 Отправить", "appd:send"),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", "appd:edit"),
+		),
+	)
+}
+
+func (b *Bot) confirmText(d *applicationDraft, now time.Time) string {
+	lines := append([]string{
+		"📝 Заявка готова к отправке",
+		"",
+	}, resubmitNote(d)...)
+	lines = append(lines,
+		"Компания: "+d.Company,
+		"ИНН: "+d.INN,
+		"Юр. лицо: "+d.LegalName,
+		"Договор: "+d.Contract,
+		"",
+		"По данным реестра ("+lookupTitle(d)+"):",
+		kppLine(d),
+		"Название: "+nz(d.RusName),
+		"Адрес: "+nz(d.RusAddress),
+	)
+	if strings.TrimSpace(d.RusErr) != "" {
+		lines = append(lines, "⚠️ "+d.RusErr)
+	}
+
+	lines = append(lines, "", "Позиции:")
+	total := 0.0
+	for i, it := range d.Items {
+		unit := it.Unit
+		if unit == "" {
+			unit = "шт"
+		}
+		lines = append(lines, fmt.Sprintf("%d) %s — %d %s × %.2f = %.2f", i+1, it.Name, it.Qty, unit, it.UnitPrice, it.Total))
+		total += it.Total
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Итого: %.2f", total),
+		fmt.Sprintf("в т.ч. НДС 22%%: %.2f", invoiceVAT(total)),
+	)
+
+	// ✅ приём закрыт — сразу говорим, каким днём оформится счёт
+	if b.cfg.Bot3IntakeMode == config.IntakeQueue {
+		if c := b.checkIntake(now); !c.Open && !c.today(now) {
+			lines = append(lines, "", "📅 "+c.closedReason(now)+" Счёт будет оформлен датой "+formatDayRu(c.BusinessDay)+".")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+const invoicePreviewCaption = "Предпросмотр счёта. Номер будет присвоен после отправки."
+
+// sendInvoicePreview: тот же шаблон счёта без номера (номер берём только при отправке).
+// soffice медленный и один на процесс, поэтому PDF собираем, только когда счёт изменился,
+// иначе пересылаем прошлый файл. Не получилось — не мешаем отправке, просто пишем почему.
+func (b *Bot) sendInvoicePreview(ctx context.Context, chatID int64, st *userAppState) {
+	tpl := strings.TrimSpace(b.cfg.Bot3InvoiceTemplatePath)
+	if tpl == "" {
+		tpl = "assets/invoice_template.xlsx"
+	}
+
+	date := time.Now().In(moscowLocation())
+	if _, queuedFor, ok := b.intakeOnSubmit(date); ok && !queuedFor.IsZero() {
+		date = queuedFor
+	}
+
+	key := invoicePreviewKey(tpl, date, &st.Draft)
+	if st.PreviewFileID != "" && st.PreviewKey == key {
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileID(st.PreviewFileID))
+		doc.Caption = invoicePreviewCaption
+		_, err := b.send(doc)
+		if err == nil {
+			return
+		}
+		b.logf("resend preview error: %v", err)
+	}
+	st.PreviewKey, st.PreviewFileID = "", ""
+
+	tempDir, err := os.MkdirTemp("", "tg3-preview-*")
+	if err != nil {
+		b.logf("preview temp dir error: %v", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	xlsxPath, err := FillInvoiceTemplateXLSX(tpl, tempDir, 0, date, st.Draft, st.Draft.Items)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "⚠️ Не удалось сформировать предпросмотр счёта: "+err.Error()))
+		return
+	}
+
+	path := xlsxPath
+	pdfPath, err := ConvertXLSXToPDFLibreOffice(ctx, b.cfg, xlsxPath, tempDir)
+	if err != nil {
+		b.logf("preview pdf convert error: %v", err)
+	} else {
+		path = pdfPath
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(path))
+	doc.Caption = invoicePreviewCaption
+	sent, err := b.send(doc)
+	if err != nil {
+		b.logf("send preview error: %v", err)
+		return
+	}
+	// запасной XLSX не запоминаем: в следующий раз снова попробуем PDF
+	if path == pdfPath && sent.Document != nil {
+		st.PreviewKey, st.PreviewFileID = key, sent.Document.FileID
+	}
+}
+
+// invoicePreviewKey — хеш всего, что попадает в предпросмотр: черновик, дата счёта и версия шаблона.
+func invoicePreviewKey(tpl string, date time.Time, d *applicationDraft) string {
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(d)
+	fmt.Fprintf(h, "%s|%s", date.Format("2006-01-02"), tpl)
+	if fi, err := os.Stat(tpl); err == nil {
+		fmt.Fprintf(h, "|%d|%d", fi.Size(), fi.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"TGBOT2/internal/sender"
+)
+
+// countingSoffice — soffice, который кладёт пустой PDF рядом и отмечает каждый запуск в runs.
+func countingSoffice(t *testing.T) (path string, runs func() int) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "runs")
+	path = filepath.Join(dir, "soffice")
+	// аргументы: --headless --nologo --nofirststartwizard --convert-to pdf --outdir DIR IN
+	script := "#!/bin/sh\necho run >> " + log + "\nbase=$(basename \"$8\" .xlsx)\necho '%PDF-1.4' > \"$7/$base.pdf\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() int {
+		b, _ := os.ReadFile(log)
+		return strings.Count(string(b), "run")
+	}
+}
+
+func TestInvoicePreviewCache(t *testing.T) {
+	api, sent := recordingTelegram(t)
+	soffice, runs := countingSoffice(t)
+
+	b := testIntakeBot(t, "")
+	b.bot, b.out = api, sender.New(api)
+	b.cfg.SofficePath = soffice
+	b.cfg.Bot3InvoiceTemplatePath = filepath.Join("..", "..", "assets", "invoice_template.xlsx")
+
+	st := &userAppState{Draft: applicationDraft{
+		Company:   "Компания 1",
+		INN:       "7707083893",
+		LegalName: "ПАО Сбербанк",
+		Contract:  "Д-1",
+		Items:     []appItem{{Name: "Бумага", Qty: 2, UnitPrice: 100, Total: 200}},
+	}}
+	preview := func() sentRequest {
+		t.Helper()
+		before := len(sent())
+		b.sendInvoicePreview(context.Background(), 1, st)
+		reqs := sent()
+		if len(reqs) != before+1 || reqs[before].Method != "sendDocument" {
+			t.Fatalf("preview requests: %+v", reqs[before:])
+		}
+		return reqs[before]
+	}
+
+	if got := preview(); got.Document != "" || runs() != 1 {
+		t.Fatalf("first preview: document=%q, soffice runs=%d; want upload and 1 run", got.Document, runs())
+	}
+	if st.PreviewFileID != "doc-1" {
+		t.Fatalf("PreviewFileID = %q, want doc-1", st.PreviewFileID)
+	}
+
+	// вернулись на проверку без изменений — тот же файл, soffice не трогаем
+	if got := preview(); got.Document != "doc-1" || runs() != 1 {
+		t.Fatalf("unchanged draft: document=%q, soffice runs=%d; want resend of doc-1 and 1 run", got.Document, runs())
+	}
+
+	// правка позиции — счёт собираем заново
+	st.Draft.Items[0].Qty, st.Draft.Items[0].Total = 3, 300
+	if got := preview(); got.Document != "" || runs() != 2 {
+		t.Fatalf("changed draft: document=%q, soffice runs=%d; want upload and 2 runs", got.Document, runs())
+	}
+	if st.PreviewFileID != "doc-2" {
+		t.Fatalf("PreviewFileID = %q, want doc-2", st.PreviewFileID)
+	}
+}
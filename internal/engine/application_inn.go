@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ИНН: 10 цифр — организация, 12 — ИП (и физлицо). Последние цифры — контрольные.
+var (
+	innWeights10  = []int{2, 4, 10, 3, 5, 9, 4, 6, 8}
+	innWeights11  = []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+	innWeights12  = []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+	reINNSpaces   = regexp.MustCompile(`[\s\-]+`)
+	reKPP         = regexp.MustCompile(`^\d{4}[\dA-Z]{2}\d{3}$`)
+	errINNDigits  = errors.New("ИНН должен состоять только из цифр.")
+	errINNControl = errors.New("ИНН не проходит проверку контрольного числа — похоже, в нём опечатка.")
+)
+
+// normalizeINN убирает пробелы/дефисы и проверяет длину и контрольные цифры.
+// Текст ошибки показывается пользователю как есть.
+func normalizeINN(s string) (string, error) {
+	inn := reINNSpaces.ReplaceAllString(strings.TrimSpace(s), "")
+	if inn == "" {
+		return "", errors.New("Введите ИНН: 10 цифр для организации или 12 цифр для ИП.")
+	}
+	for _, r := range inn {
+		if r < '0' || r > '9' {
+			return "", errINNDigits
+		}
+	}
+
+	switch len(inn) {
+	case 10:
+		if innControl(inn, innWeights10) != inn[9] {
+			return "", errINNControl
+		}
+	case 12:
+		if innControl(inn, innWeights11) != inn[10] || innControl(inn, innWeights12) != inn[11] {
+			return "", errINNControl
+		}
+	default:
+		return "", fmt.Errorf("В ИНН %d цифр, а должно быть 10 (организация) или 12 (ИП).", len(inn))
+	}
+	// код региона 00 не выдаётся, а «0000000000» проходит контрольное число
+	if inn[:2] == "00" {
+		return "", errINNControl
+	}
+	return inn, nil
+}
+
+// innControl — контрольная цифра по весам: сумма произведений mod 11 mod 10.
+func innControl(inn string, weights []int) byte {
+	sum := 0
+	for i, w := range weights {
+		sum += int(inn[i]-'0') * w
+	}
+	return byte('0' + sum%11%10)
+}
+
+// normalizeKPP: 9 символов — код налоговой (4 цифры), причина постановки (2 цифры или A–Z), номер (3 цифры).
+func normalizeKPP(s string) (string, error) {
+	kpp := strings.ToUpper(reINNSpaces.ReplaceAllString(strings.TrimSpace(s), ""))
+	if !reKPP.MatchString(kpp) {
+		return "", errors.New("КПП — 9 символов: 4 цифры, 2 цифры или латинские буквы, ещё 3 цифры (например, 773601001).")
+	}
+	return kpp, nil
+}
+
+func isSoleProprietorINN(inn string) bool {
+	return len(inn) == 12
+}
+
+// draftKPP — КПП покупателя для счёта: из реестра, иначе введённый вручную. У ИП КПП нет.
+func draftKPP(d *applicationDraft) string {
+	if isSoleProprietorINN(d.INN) {
+		return ""
+	}
+	if kpp := strings.TrimSpace(d.RusKPP); kpp != "" {
+		return kpp
+	}
+	return strings.TrimSpace(d.KPP)
+}
+
+// buyerName — название покупателя в счёте: из реестра, иначе ввод; ИП — с префиксом «ИП».
+func buyerName(d *applicationDraft) string {
+	name := strings.TrimSpace(d.RusName)
+	if name == "" {
+		name = strings.TrimSpace(d.LegalName)
+	}
+	if name == "" || !isSoleProprietorINN(d.INN) {
+		return name
+	}
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "ип ") || strings.HasPrefix(lower, "индивидуальный предприниматель") {
+		return name
+	}
+	return "ИП " + name
+}
+
+// kppLine — КПП для сводок: у ИП явно пишем, что его нет.
+func kppLine(d *applicationDraft) string {
+	if isSoleProprietorINN(d.INN) {
+		return "КПП: — (ИП)"
+	}
+	if strings.TrimSpace(d.RusKPP) == "" && strings.TrimSpace(d.KPP) != "" {
+		return "КПП: " + d.KPP + " (введён вручную)"
+	}
+	return "КПП: " + nz(d.RusKPP)
+}
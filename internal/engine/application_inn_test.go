@@ -0,0 +1,89 @@
+package engine
+
+import "testing"
+
+func TestNormalizeINN(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "7707083893", want: "7707083893"},     // ПАО Сбербанк
+		{in: "7736207543", want: "7736207543"},     // ООО Яндекс
+		{in: " 77 0708-3893 ", want: "7707083893"}, // пробелы и дефисы убираем
+		{in: "500100732259", want: "500100732259"}, // ИП: обе контрольные цифры
+		{in: "7707083894", wantErr: true},          // опечатка в контрольной цифре
+		{in: "7707038893", wantErr: true},          // переставлены цифры
+		{in: "500100732258", wantErr: true},        // ИП: не сходится 12-я
+		{in: "500100732269", wantErr: true},        // ИП: не сходится 11-я
+		{in: "77070838931", wantErr: true},         // 11 цифр
+		{in: "123456789", wantErr: true},           // 9 цифр
+		{in: "77070838ЗЗ", wantErr: true},          // буквы вместо цифр
+		{in: "0000000000", wantErr: true},          // проходит контрольное число, но региона 00 нет
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeINN(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeINN(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeINN(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeKPP(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "773601001", want: "773601001"},
+		{in: "7736AB001", want: "7736AB001"},
+		{in: "7736ab001", want: "7736AB001"},
+		{in: " 7736 01 001 ", want: "773601001"},
+		{in: "77360100", wantErr: true},   // 8 символов
+		{in: "7736010011", wantErr: true}, // 10 символов
+		{in: "77A601001", wantErr: true},  // буква в коде налоговой
+		{in: "7736АБ001", wantErr: true},  // кириллица
+	}
+	for _, tt := range tests {
+		got, err := normalizeKPP(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeKPP(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeKPP(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestBuyerLine(t *testing.T) {
+	ip := &applicationDraft{INN: "500100732259", LegalName: "Иванов Иван Иванович", RusKPP: "500101001", KPP: "500101001"}
+	if got := buyerName(ip); got != "ИП Иванов Иван Иванович" {
+		t.Errorf("buyerName(ИП) = %q", got)
+	}
+	if got := draftKPP(ip); got != "" {
+		t.Errorf("draftKPP(ИП) = %q, want empty", got)
+	}
+	if got := buyerName(&applicationDraft{INN: "500100732259", RusName: "ИП Иванов Иван Иванович"}); got != "ИП Иванов Иван Иванович" {
+		t.Errorf("buyerName(ИП из реестра) = %q", got)
+	}
+
+	org := &applicationDraft{INN: "7707083893", LegalName: "Сбербанк", RusName: "ПАО СБЕРБАНК", KPP: "773601002"}
+	if got := buyerName(org); got != "ПАО СБЕРБАНК" {
+		t.Errorf("buyerName(org) = %q", got)
+	}
+	if got := draftKPP(org); got != "773601002" {
+		t.Errorf("draftKPP(org, manual) = %q", got)
+	}
+	org.RusKPP = "773601001"
+	if got := draftKPP(org); got != "773601001" {
+		t.Errorf("draftKPP(org, registry) = %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/config"
+)
+
+// intakeCheck — открыт ли сейчас приём заявок (окно: BOT3_INTAKE_OPEN .. конец приёма по календарю).
+type intakeCheck struct {
+	Open     bool
+	Deadline time.Time // конец приёма сегодня; zero — сегодня нерабочий день
+	OpensAt  time.Time // когда приём откроется (если закрыт)
+	// рабочий день, которым оформляется заявка: сегодня или (если приём закрыт) ближайший следующий
+	BusinessDay time.Time
+}
+
+func (b *Bot) checkIntake(now time.Time) intakeCheck {
+	now = now.In(moscowLocation())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	d, working := b.cal.Deadline(now)
+	if working {
+		opens := today.Add(b.cfg.Bot3IntakeOpen)
+		if now.Before(opens) {
+			// ещё рано — заявка уйдёт сегодняшним днём, когда приём откроется
+			return intakeCheck{Deadline: d, OpensAt: opens, BusinessDay: today}
+		}
+		if !now.After(d) {
+			return intakeCheck{Open: true, Deadline: d, BusinessDay: today}
+		}
+	}
+
+	next := b.cal.NextWorkingDay(now)
+	return intakeCheck{Deadline: d, OpensAt: next.Add(b.cfg.Bot3IntakeOpen), BusinessDay: next}
+}
+
+// today: заявка оформляется сегодняшним днём (открыто или рабочий день, но приём ещё не начался).
+func (c intakeCheck) today(now time.Time) bool {
+	now = now.In(moscowLocation())
+	y, m, d := now.Date()
+	by, bm, bd := c.BusinessDay.In(moscowLocation()).Date()
+	return y == by && m == bm && d == bd
+}
+
+// closedReason: почему приём сейчас закрыт.
+func (c intakeCheck) closedReason(now time.Time) string {
+	switch {
+	case c.Deadline.IsZero():
+		return "Сегодня нерабочий день, заявки не принимаются."
+	case now.Before(c.Deadline):
+		return "Приём заявок ещё не начался: он открывается в " + c.OpensAt.Format("15:04") + "."
+	default:
+		return "Приём заявок на сегодня закончился в " + c.Deadline.Format("15:04") + "."
+	}
+}
+
+// intakeAllowsStart: «📝 Составить заявку» — предупреждаем о времени приёма.
+// false — заявку сейчас не принимаем (режим reject).
+func (b *Bot) intakeAllowsStart(chatID int64, now time.Time) bool {
+	c := b.checkIntake(now)
+
+	var text string
+	switch {
+	case c.Open:
+		if b.cal.IsShortDay(now) {
+			text = fmt.Sprintf("⚠️ Сегодня сокращённый день: приём заявок до %s.", c.Deadline.Format("15:04"))
+		}
+	case b.cfg.Bot3IntakeMode == config.IntakeReject:
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⛔ %s\nПриём откроется %s в %s.",
+			c.closedReason(now), formatDayRu(c.OpensAt), c.OpensAt.Format("15:04")))
+		msg.ReplyMarkup = mainMenuKeyboard()
+		_, _ = b.send(msg)
+		return false
+	case b.cfg.Bot3IntakeMode == config.IntakeQueue && c.today(now):
+		text = fmt.Sprintf("⚠️ %s\nЗаявку можно составить и отправить сейчас — она будет оформлена сегодняшним днём.",
+			c.closedReason(now))
+	case b.cfg.Bot3IntakeMode == config.IntakeQueue:
+		text = fmt.Sprintf("⚠️ %s\nЗаявку можно составить сейчас — она будет оформлена рабочим днём %s.",
+			c.closedReason(now), formatDayRu(c.BusinessDay))
+	default:
+		text = fmt.Sprintf("⚠️ %s\nЗаявка будет обработана в ближайший рабочий день — %s.",
+			c.closedReason(now), formatDayRu(c.BusinessDay))
+	}
+
+	if text != "" {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, text))
+	}
+	return true
+}
+
+// intakeOnSubmit — проверка при отправке заявки. queuedFor — рабочий день, которым
+// оформляем заявку вне окна приёма (zero — обычная заявка). ok=false — не принимаем (reject).
+func (b *Bot) intakeOnSubmit(now time.Time) (c intakeCheck, queuedFor time.Time, ok bool) {
+	c = b.checkIntake(now)
+	if c.Open {
+		return c, time.Time{}, true
+	}
+	switch b.cfg.Bot3IntakeMode {
+	case config.IntakeReject:
+		return c, time.Time{}, false
+	case config.IntakeQueue:
+		// рабочий день, но приём ещё не открылся — обычная заявка сегодняшним днём
+		if c.today(now) {
+			return c, time.Time{}, true
+		}
+		return c, c.BusinessDay, true
+	}
+	return c, time.Time{}, true
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"TGBOT2/internal/calendar"
+	"TGBOT2/internal/config"
+)
+
+// календарь для тестов: 03.11.2026 (вт) — сокращённый день, 04.11.2026 (ср) — праздник
+const testCalendarJSON = `{
+  "deadline": "15:50",
+  "short_day_deadline": "14:50",
+  "holidays": ["2026-11-04"],
+  "short_days": ["2026-11-03"],
+  "working_weekends": [],
+  "deadlines": {}
+}`
+
+func testIntakeBot(t *testing.T, mode string) *Bot {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	if err := os.WriteFile(path, []byte(testCalendarJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := calendar.Load(path)
+	if err != nil {
+		t.Fatalf("calendar.Load: %v", err)
+	}
+	return &Bot{cal: cal, cfg: &config.Config{Bot3IntakeOpen: 9 * time.Hour, Bot3IntakeMode: mode}}
+}
+
+func msk(day string, hhmm string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", day+" "+hhmm, moscowLocation())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCheckIntake(t *testing.T) {
+	b := testIntakeBot(t, config.IntakeQueue)
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		wantOpen     bool
+		wantDeadline string // "15:04"; "" — нерабочий день
+		wantBusiness string // день оформления
+		wantOpensAt  string // когда откроется, если закрыто
+		wantQueued   string // intakeOnSubmit в режиме queue; "" — обычная заявка
+	}{
+		{name: "before opening", now: msk("2026-10-19", "07:30"), wantDeadline: "15:50", wantBusiness: "2026-10-19", wantOpensAt: "2026-10-19 09:00"},
+		{name: "window opens", now: msk("2026-10-19", "09:00"), wantOpen: true, wantDeadline: "15:50", wantBusiness: "2026-10-19"},
+		{name: "in window", now: msk("2026-10-19", "12:00"), wantOpen: true, wantDeadline: "15:50", wantBusiness: "2026-10-19"},
+		{name: "at deadline", now: msk("2026-10-19", "15:50"), wantOpen: true, wantDeadline: "15:50", wantBusiness: "2026-10-19"},
+		{name: "after deadline", now: msk("2026-10-19", "16:10"), wantDeadline: "15:50", wantBusiness: "2026-10-20", wantOpensAt: "2026-10-20 09:00", wantQueued: "2026-10-20"},
+		{name: "friday evening", now: msk("2026-10-23", "18:00"), wantDeadline: "15:50", wantBusiness: "2026-10-26", wantOpensAt: "2026-10-26 09:00", wantQueued: "2026-10-26"},
+		{name: "short day in window", now: msk("2026-11-03", "14:30"), wantOpen: true, wantDeadline: "14:50", wantBusiness: "2026-11-03"},
+		{name: "short day after deadline skips holiday", now: msk("2026-11-03", "15:00"), wantDeadline: "14:50", wantBusiness: "2026-11-05", wantOpensAt: "2026-11-05 09:00", wantQueued: "2026-11-05"},
+		{name: "holiday", now: msk("2026-11-04", "10:00"), wantBusiness: "2026-11-05", wantOpensAt: "2026-11-05 09:00", wantQueued: "2026-11-05"},
+		{name: "weekend", now: msk("2026-10-24", "10:00"), wantBusiness: "2026-10-26", wantOpensAt: "2026-10-26 09:00", wantQueued: "2026-10-26"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := b.checkIntake(tt.now)
+			if c.Open != tt.wantOpen {
+				t.Errorf("Open = %v, want %v", c.Open, tt.wantOpen)
+			}
+			gotDeadline := ""
+			if !c.Deadline.IsZero() {
+				gotDeadline = c.Deadline.Format("15:04")
+			}
+			if gotDeadline != tt.wantDeadline {
+				t.Errorf("Deadline = %q, want %q", gotDeadline, tt.wantDeadline)
+			}
+			if got := c.BusinessDay.Format("2006-01-02"); got != tt.wantBusiness {
+				t.Errorf("BusinessDay = %s, want %s", got, tt.wantBusiness)
+			}
+			if !tt.wantOpen {
+				if got := c.OpensAt.Format("2006-01-02 15:04"); got != tt.wantOpensAt {
+					t.Errorf("OpensAt = %s, want %s", got, tt.wantOpensAt)
+				}
+			}
+
+			_, queued, ok := b.intakeOnSubmit(tt.now)
+			if !ok {
+				t.Fatal("queue mode rejected the submission")
+			}
+			gotQueued := ""
+			if !queued.IsZero() {
+				gotQueued = queued.Format("2006-01-02")
+			}
+			if gotQueued != tt.wantQueued {
+				t.Errorf("queuedFor = %q, want %q", gotQueued, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestIntakeOnSubmitModes(t *testing.T) {
+	evening := msk("2026-10-19", "16:10")
+	morning := msk("2026-10-19", "07:30")
+
+	if _, _, ok := testIntakeBot(t, config.IntakeReject).intakeOnSubmit(evening); ok {
+		t.Error("reject mode accepted a late submission")
+	}
+	if _, _, ok := testIntakeBot(t, config.IntakeReject).intakeOnSubmit(morning); ok {
+		t.Error("reject mode accepted a submission before opening")
+	}
+	if _, queued, ok := testIntakeBot(t, config.IntakeOff).intakeOnSubmit(evening); !ok || !queued.IsZero() {
+		t.Errorf("off mode: queued=%v ok=%v; want zero, true", queued, ok)
+	}
+}
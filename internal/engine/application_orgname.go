@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// порог похожести, с которого название пользователя принимаем как совпадающее с реестром
+const orgNameMatchThreshold = 0.85
+
+// слово ниже этой похожести пары не нашло; слова до orgShortToken рун — только точное совпадение
+const (
+	orgTokenMin   = 0.75
+	orgShortToken = 3
+)
+
+var (
+	reOrgClean  = regexp.MustCompile(`[^\pL\pN]+`)
+	reOrgDigits = regexp.MustCompile(`\d+`)
+)
+
+// полные ОПФ — убираем целиком до разбиения на слова (длинные раньше коротких)
+var orgLegalForms = []string{
+	"публичное акционерное общество",
+	"непубличное акционерное общество",
+	"закрытое акционерное общество",
+	"открытое акционерное общество",
+	"акционерное общество",
+	"общество с ограниченной ответственностью",
+	"индивидуальный предприниматель",
+}
+
+// сокращённые ОПФ — отдельные слова, подстрокой их не трогаем («Пао» в «Паоло» остаётся)
+var orgLegalAbbr = map[string]bool{
+	"ооо": true, "оао": true, "зао": true, "пао": true, "ао": true, "нао": true, "ип": true,
+}
+
+// orgNameTokens: нижний регистр, ё→е, без ОПФ и кавычек, разбито по любым не-буквам/цифрам.
+// «ООО Ромашка-Трейд» и «ООО "Ромашка Трейд"» дают одинаковое [ромашка трейд].
+func orgNameTokens(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	s = strings.Join(strings.Fields(reOrgClean.ReplaceAllString(s, " ")), " ")
+	for _, f := range orgLegalForms {
+		s = strings.ReplaceAll(s, f, " ")
+	}
+
+	var tokens []string
+	for _, t := range strings.Fields(s) {
+		if !orgLegalAbbr[t] {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// orgNameSimilarity — от 0 до 1.
+// Склеенные слова совпали точно («РомашкаТрейд» против «Ромашка Трейд») — 1.
+// Иначе: числа в названиях должны совпасть точно («Альфа-11» и «Альфа-12» — разные юрлица),
+// каждое слово должно найти пару во втором названии (порядок и опечатки в длинных словах не страшны),
+// итог — средняя похожесть слов в обе стороны.
+func orgNameSimilarity(a, b string) float64 {
+	ta, tb := orgNameTokens(a), orgNameTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	if strings.Join(ta, "") == strings.Join(tb, "") {
+		return 1
+	}
+	if strings.Join(reOrgDigits.FindAllString(strings.Join(ta, " "), -1), " ") !=
+		strings.Join(reOrgDigits.FindAllString(strings.Join(tb, " "), -1), " ") {
+		return 0
+	}
+
+	ab, okA := tokenCoverage(ta, tb)
+	ba, okB := tokenCoverage(tb, ta)
+	if !okA || !okB {
+		return 0
+	}
+	return (ab + ba) / 2
+}
+
+// tokenCoverage — средняя лучшая похожесть слов из a на слова из b.
+// false — какое-то слово пары не нашло: короткие слова и слова с цифрами сравниваются только точно.
+func tokenCoverage(a, b []string) (float64, bool) {
+	sum := 0.0
+	for _, x := range a {
+		best := 0.0
+		for _, y := range b {
+			if s := tokenSimilarity(x, y); s > best {
+				best = s
+			}
+		}
+		if best < orgTokenMin {
+			return 0, false
+		}
+		sum += best
+	}
+	return sum / float64(len(a)), true
+}
+
+func tokenSimilarity(x, y string) float64 {
+	if x == y {
+		return 1
+	}
+	// «ПК»/«ПО», «А»/«Б», «11»/«12» — одна буква решает, опечаткой это не считаем
+	if utf8.RuneCountInString(x) <= orgShortToken || utf8.RuneCountInString(y) <= orgShortToken ||
+		reOrgDigits.MatchString(x) || reOrgDigits.MatchString(y) {
+		return 0
+	}
+	return stringSimilarity(x, y)
+}
+
+// stringSimilarity — 1 - расстояние Левенштейна / длина большей строки (по рунам).
+func stringSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := max(len(ra), len(rb))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func orgNamesMatch(a, b string) bool {
+	// если вдруг реестр не дал имя — не блокируем
+	if len(orgNameTokens(b)) == 0 {
+		return true
+	}
+	return orgNameSimilarity(a, b) >= orgNameMatchThreshold
+}
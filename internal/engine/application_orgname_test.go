@@ -0,0 +1,55 @@
+package engine
+
+import "testing"
+
+func TestOrgNamesMatch(t *testing.T) {
+	tests := []struct {
+		user, registry string
+		want           bool
+	}{
+		// ОПФ, кавычки, дефисы, регистр
+		{"ООО Ромашка-Трейд", `ООО "Ромашка Трейд"`, true},
+		{"Ромашка Трейд", "Общество с ограниченной ответственностью «РОМАШКА-ТРЕЙД»", true},
+		{`ООО "Ромашка"`, "ООО «Ромашка»", true},
+		{"Ромашка", "ООО Ромашка", true},
+		{"ромашкатрейд", "ООО Ромашка Трейд", true},
+		{"Трейд Ромашка", "ООО Ромашка Трейд", true},
+		{"Сбербанк", "ПАО СБЕРБАНК", true},
+		{"АО Альфа", "Акционерное общество «Альфа»", true},
+		{"Иванов Иван Иванович", "ИП Иванов Иван Иванович", true},
+		{"Индивидуальный предприниматель Иванов Иван Иванович", "ИП Иванов Иван Иванович", true},
+		{"Паоло", "ООО Паоло", true}, // «ао» внутри слова не ОПФ
+		{"Ёлка", "ООО Елка", true},
+
+		// опечатка в длинном слове
+		{"Ромашка Трейт", "ООО Ромашка Трейд", true},
+
+		// почти совпадает, но это другое юрлицо
+		{"Альфа-11", "ООО Альфа-12", false},
+		{"Ромашка 1", "ООО Ромашка 2", false},
+		{"Ромашка", "ООО Ромашка 2", false},
+		{"Альфа11", "ООО Альфа 12", false},
+		{"Ромашка А", "ООО Ромашка Б", false},
+		{"Сигма ПК", "ООО Сигма ПО", false},
+		{"Север Строй Инвест Групп Проект Сервис М", "ООО Север Строй Инвест Групп Проект Сервис Н", false},
+		{"Ромашка", "ООО Ромашка Трейд", false},
+		{"Лютик", "ООО Ромашка", false},
+		{"ООО", "ООО Ромашка", false},
+
+		// реестр не дал название — не блокируем
+		{"Что угодно", "", true},
+	}
+	for _, tt := range tests {
+		if got := orgNamesMatch(tt.user, tt.registry); got != tt.want {
+			t.Errorf("orgNamesMatch(%q, %q) = %v (score %.2f), want %v",
+				tt.user, tt.registry, got, orgNameSimilarity(tt.user, tt.registry), tt.want)
+		}
+	}
+}
+
+func TestOrgNameTokens(t *testing.T) {
+	got := orgNameTokens(`Общество с ограниченной ответственностью "Ромашка-Трейд" (ООО)`)
+	if len(got) != 2 || got[0] != "ромашка" || got[1] != "трейд" {
+		t.Errorf("tokens = %q", got)
+	}
+}
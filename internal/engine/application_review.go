@@ -0,0 +1,415 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// поля позиции, которые можно исправить на экране проверки
+const (
+	itemFieldName  = "name"
+	itemFieldQty   = "qty"
+	itemFieldUnit  = "unit"
+	itemFieldPrice = "price"
+	itemFieldTotal = "total"
+)
+
+var itemFields = []struct{ Key, Title string }{
+	{itemFieldName, "Наименование"},
+	{itemFieldQty, "Количество"},
+	{itemFieldUnit, "Ед. изм."},
+	{itemFieldPrice, "Цена"},
+	{itemFieldTotal, "Сумма"},
+}
+
+func itemFieldTitle(field string) string {
+	for _, f := range itemFields {
+		if f.Key == field {
+			return f.Title
+		}
+	}
+	return field
+}
+
+// goReview — экран правки заявки (из подтверждения по «Изменить»). Дальнейшие правки возвращают сюда же.
+func (b *Bot) goReview(chatID int64, st *userAppState) {
+	st.Reviewing = true
+	st.Stage = stageReview
+	st.EditField = ""
+
+	// убираем клавиатуру предыдущего шага («Пропуск» и т.п.), оставляем отмену/поддержку
+	msg := tgbotapi.NewMessage(chatID, "Исправьте заявку кнопками под сводкой и нажмите «✅ Готово».")
+	msg.ReplyMarkup = stepControlKeyboard()
+	_, _ = b.send(msg)
+
+	b.sendReview(chatID, st)
+}
+
+// afterItemAdded: новая позиция добавлена — из проверки возвращаемся к ней, иначе спрашиваем про ещё одну.
+func (b *Bot) afterItemAdded(chatID int64, st *userAppState) {
+	if st.Reviewing {
+		b.goReview(chatID, st)
+		return
+	}
+	st.Stage = stageAskMoreItems
+	b.promptForStage(chatID, st)
+}
+
+func (b *Bot) sendReview(chatID int64, st *userAppState) {
+	msg := tgbotapi.NewMessage(chatID, reviewText(&st.Draft))
+	msg.ReplyMarkup = reviewKeyboard(&st.Draft)
+	_, _ = b.send(msg)
+}
+
+func reviewText(d *applicationDraft) string {
+	total := 0.0
+	lines := append(resubmitNote(d),
+		"📋 Заявка",
+		"Компания: "+d.Company,
+		"ИНН: "+d.INN,
+		"Юр. лицо: "+d.LegalName,
+		"Договор: "+d.Contract,
+		"",
+		"Позиции:",
+	)
+	for i, it := range d.Items {
+		unit := it.Unit
+		if unit == "" {
+			unit = "шт"
+		}
+		lines = append(lines, fmt.Sprintf("%d) %s — %d %s × %.2f = %.2f", i+1, it.Name, it.Qty, unit, it.UnitPrice, it.Total))
+		total += it.Total
+	}
+	lines = append(lines, "", fmt.Sprintf("Итого: %.2f", total))
+	return strings.Join(lines, "\n")
+}
+
+func reviewKeyboard(d *applicationDraft) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := range d.Items {
+		n := strconv.Itoa(i)
+		row := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✏️ №%d", i+1), "appd:item:"+n),
+		}
+		if i > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬆️", "appd:up:"+n))
+		}
+		if i < len(d.Items)-1 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬇️", "appd:down:"+n))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑", "appd:del:"+n))
+		rows = append(rows, row)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Позиция", "appd:add"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("ИНН", "appd:inn"),
+			tgbotapi.NewInlineKeyboardButtonData("Юр. лицо", "appd:name"),
+			tgbotapi.NewInlineKeyboardButtonData("Договор", "appd:contract"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Готово", "appd:done"),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// HandleApplicationCallback: inline-кнопки экрана проверки заявки в личке.
+func (b *Bot) HandleApplicationCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	if cq == nil || cq.Message == nil || cq.Message.Chat == nil || cq.From == nil {
+		return
+	}
+	if !cq.Message.Chat.IsPrivate() || !(strings.HasPrefix(cq.Data, "appd:") || strings.HasPrefix(cq.Data, "appfix:")) {
+		return
+	}
+
+	_, _ = b.bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+
+	if b.profile.BlockUnblock {
+		blocked, err := storage.IsUserBlockedByTelegramID(b.db, cq.From.ID)
+		if err != nil || blocked {
+			return
+		}
+	}
+
+	chatID := cq.Message.Chat.ID
+	st := b.getOrCreateState(cq.From.ID)
+	defer b.saveState(cq.From.ID, st)
+
+	if strings.HasPrefix(cq.Data, "appfix:") {
+		b.reopenRejectedApplication(chatID, st, strings.TrimPrefix(cq.Data, "appfix:"))
+		return
+	}
+
+	// покупатель из адресной книги — кнопки под вопросом про ИНН
+	if strings.HasPrefix(cq.Data, "appd:buyer:") {
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		if st.Stage != stageAwaitINN {
+			return
+		}
+		b.pickSavedBuyer(ctx, chatID, cq.From.ID, st, strings.TrimPrefix(cq.Data, "appd:buyer:"))
+		return
+	}
+
+	// «Взять название из реестра» — живёт на шаге названия, а не на проверке
+	if cq.Data == "appd:usereg" {
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		if st.Stage != stageAwaitLegalName || strings.TrimSpace(st.Draft.RusName) == "" {
+			return
+		}
+		st.Draft.LegalName = st.Draft.RusName
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Юр. лицо: "+st.Draft.LegalName+" ✅"))
+		b.afterLegalName(chatID, st)
+		return
+	}
+
+	// кнопки старой сводки: заявка уже отправлена, отменена или пользователь на другом шаге
+	if st.Stage != stageReview && st.Stage != stageEditItemField && st.Stage != stageConfirm {
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		return
+	}
+
+	action, arg, _ := strings.Cut(strings.TrimPrefix(cq.Data, "appd:"), ":")
+
+	// ✅ отправить можно только с экрана подтверждения — после правок его показываем заново
+	if action == "send" {
+		if st.Stage != stageConfirm {
+			return
+		}
+		// сводку больше не трогаем — чтобы не отправить заявку дважды
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		b.sendForApproval(ctx, chatID, cq.From, cq.Message.MessageID, st)
+		return
+	}
+	st.Stage = stageReview
+
+	switch action {
+	case "edit":
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		b.goReview(chatID, st)
+
+	case "done":
+		b.dropInlineKeyboard(chatID, cq.Message.MessageID)
+		b.goConfirm(ctx, chatID, st)
+
+	case "item":
+		i, ok := reviewItemIndex(st, arg)
+		if !ok {
+			return
+		}
+		var rows [][]tgbotapi.InlineKeyboardButton
+		var row []tgbotapi.InlineKeyboardButton
+		for _, f := range itemFields {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(f.Title, fmt.Sprintf("appd:f:%d:%s", i, f.Key)))
+			if len(row) == 3 {
+				rows = append(rows, row)
+				row = nil
+			}
+		}
+		rows = append(rows, row)
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Позиция №%d: %s\nЧто исправить?", i+1, st.Draft.Items[i].Name))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+		_, _ = b.send(msg)
+
+	case "f":
+		idx, field, _ := strings.Cut(arg, ":")
+		i, ok := reviewItemIndex(st, idx)
+		if !ok || itemFieldTitle(field) == field {
+			return
+		}
+		st.Stage = stageEditItemField
+		st.EditItem = i
+		st.EditField = field
+		b.promptItemField(chatID, st)
+
+	case "del", "up", "down":
+		i, ok := reviewItemIndex(st, arg)
+		if !ok {
+			return
+		}
+		items := st.Draft.Items
+		switch action {
+		case "del":
+			st.Draft.Items = append(items[:i:i], items[i+1:]...)
+		case "up":
+			if i > 0 {
+				items[i-1], items[i] = items[i], items[i-1]
+			}
+		case "down":
+			if i < len(items)-1 {
+				items[i+1], items[i] = items[i], items[i+1]
+			}
+		}
+
+		if len(st.Draft.Items) == 0 {
+			_, _ = b.send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, "Все позиции удалены."))
+			st.Stage = stageAwaitItemName
+			st.CurItem = appItem{}
+			b.promptForStage(chatID, st)
+			return
+		}
+		// ✅ порядок и удаление — правим ту же сводку, без новых сообщений
+		_, _ = b.send(tgbotapi.NewEditMessageTextAndMarkup(chatID, cq.Message.MessageID, reviewText(&st.Draft), reviewKeyboard(&st.Draft)))
+
+	case "add":
+		st.Stage = stageAwaitItemName
+		st.CurItem = appItem{}
+		b.promptForStage(chatID, st)
+
+	case "inn":
+		// новый ИНН — заново сверяем название с реестром
+		st.Stage = stageAwaitINN
+		b.promptForStage(chatID, st)
+
+	case "name":
+		st.Stage = stageAwaitLegalName
+		b.promptForStage(chatID, st)
+
+	case "contract":
+		st.Stage = stageAwaitContract
+		b.promptForStage(chatID, st)
+	}
+}
+
+func (b *Bot) dropInlineKeyboard(chatID int64, messageID int) {
+	_, _ = b.send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+}
+
+func reviewItemIndex(st *userAppState, s string) (int, bool) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i >= len(st.Draft.Items) {
+		return 0, false
+	}
+	return i, true
+}
+
+func (b *Bot) promptItemField(chatID int64, st *userAppState) {
+	if st.EditItem < 0 || st.EditItem >= len(st.Draft.Items) {
+		b.goReview(chatID, st)
+		return
+	}
+	it := st.Draft.Items[st.EditItem]
+
+	var current string
+	switch st.EditField {
+	case itemFieldName:
+		current = it.Name
+	case itemFieldQty:
+		current = strconv.FormatInt(it.Qty, 10)
+	case itemFieldUnit:
+		current = nz(it.Unit)
+	case itemFieldPrice:
+		current = fmt.Sprintf("%.2f", it.UnitPrice)
+	case itemFieldTotal:
+		current = fmt.Sprintf("%.2f", it.Total)
+	}
+
+	text := fmt.Sprintf("Позиция №%d, %s.\nСейчас: %s\nВведите новое значение:", st.EditItem+1, strings.ToLower(itemFieldTitle(st.EditField)), current)
+	switch st.EditField {
+	case itemFieldQty, itemFieldPrice:
+		text += "\n(сумма по позиции пересчитается)"
+	case itemFieldTotal:
+		if it.Qty > 1 {
+			text += "\n(цена за единицу пересчитается: сумма / количество)"
+		}
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = stepControlKeyboard()
+	_, _ = b.send(msg)
+}
+
+// applyItemField меняет поле позиции и держит qty × цена = сумма.
+// Возвращает текст ошибки для пользователя или "".
+func applyItemField(it *appItem, field, txt string) string {
+	txt = strings.TrimSpace(txt)
+	switch field {
+	case itemFieldName:
+		it.Name = txt
+
+	case itemFieldUnit:
+		it.Unit = txt
+
+	case itemFieldQty:
+		q, err := strconv.ParseInt(txt, 10, 64)
+		if err != nil || q <= 0 {
+			return "Введите количество числом (например: 1, 2, 10)."
+		}
+		it.Qty = q
+		it.Total = roundKopecks(float64(q) * it.UnitPrice)
+
+	case itemFieldPrice:
+		p, err := parseMoney(txt)
+		if err != nil || p <= 0 {
+			return "Не смог распознать цену. Пример: 1000 или 1 000"
+		}
+		it.UnitPrice = p
+		it.Total = roundKopecks(float64(it.Qty) * p)
+
+	case itemFieldTotal:
+		s, err := parseMoney(txt)
+		if err != nil || s <= 0 {
+			return "Не смог распознать сумму. Пример: 1000000 или 1 000 000"
+		}
+		price := roundKopecks(s / float64(it.Qty))
+		if math.Abs(price*float64(it.Qty)-s) > 0.0001 {
+			return fmt.Sprintf("Сумма %.2f не делится на количество %d без дробных копеек. Исправьте цену за единицу или количество.", s, it.Qty)
+		}
+		it.UnitPrice = price
+		it.Total = s
+	}
+	return ""
+}
+
+func roundKopecks(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// reopenRejectedApplication: «✏️ Исправить заявку» — черновик отклонённой заявки снова
+// открывается на экране правки, с причиной от проверяющего.
+func (b *Bot) reopenRejectedApplication(chatID int64, st *userAppState, arg string) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return
+	}
+	app, ok, err := storage.GetApplication(b.db, id)
+	if err != nil {
+		b.logf("GetApplication error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось открыть заявку (ошибка БД)."))
+		return
+	}
+	if !ok || app.UserChatID != chatID {
+		return
+	}
+	if app.Status != storage.AppStatusRejected {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Заявка по счёту № %d уже исправлена и отправлена повторно.", app.InvoiceNo)))
+		return
+	}
+
+	var draft applicationDraft
+	if err := json.Unmarshal([]byte(app.DraftJSON), &draft); err != nil || len(draft.Items) == 0 {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Черновик этой заявки не сохранился, составьте её заново."))
+		return
+	}
+	draft.QueuedFor = 0
+	draft.ResubmitOf = app.ID
+	draft.ResubmitInvoiceNo = app.InvoiceNo
+	draft.RejectReason = app.RejectReason
+
+	if st.Stage != stageIdle && st.Draft.ResubmitOf != app.ID {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Незавершённая заявка заменена заявкой на исправление."))
+	}
+	*st = userAppState{Stage: stageReview, Draft: draft, Reviewing: true}
+
+	_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Исправляем заявку по счёту № %d.\nПричина: %s", app.InvoiceNo, nz(app.RejectReason))))
+	b.goReview(chatID, st)
+}
@@ -0,0 +1,938 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/companylookup"
+	"TGBOT2/internal/storage"
+)
+
+const (
+	btnMakeApplication = "📝 Составить заявку"
+	btnCancel          = "❌ Отмена"
+	btnSupport         = "🆘 Поддержка"
+	btnSkip            = "⏭ Пропуск"
+	btnContinue        = "▶️ Продолжить"
+	btnAddItem         = "➕ Добавить позицию"
+	btnFinishItems     = "✅ Готово"
+
+	company1 = "Компания 1"
+	company2 = "Компания 2"
+	company3 = "Компания 3"
+)
+
+type appStage int
+
+const (
+	stageIdle appStage = iota
+	stageChooseCompany
+	stageAwaitINN
+	stageAwaitLegalName
+	stageAwaitItemName
+	stageAwaitItemQty
+	stageAwaitItemUnit
+	stageAwaitItemUnitPrice
+	stageAwaitItemLineTotal
+	stageAskMoreItems
+	stageAwaitContract
+	stageSupportQuestion
+	stageAwaitContinue // пауза
+	stageReview        // проверка заявки перед отправкой (inline-кнопки)
+	stageEditItemField // ввод нового значения поля позиции из проверки
+	stageConfirm       // итоговая сводка и предпросмотр счёта: «Отправить» / «Изменить»
+	stageAwaitKPP      // реестр не дал КПП организации — спрашиваем у пользователя
+)
+
+type applicationDraft struct {
+	Company   string
+	INN       string
+	LegalName string
+	Contract  string
+
+	Items []appItem
+	// суммарно по позициям (заполняем перед отправкой в approval)
+	TotalSum float64
+
+	// данные реестра по ИНН (поля названы по первому источнику — Rusprofile, так лежат в сохранённых черновиках)
+	RusKPP     string
+	RusName    string
+	RusAddress string
+	RusErr     string
+	// КПП, введённый пользователем, когда реестр его не вернул (у ИП КПП нет)
+	KPP string `json:",omitempty"`
+	// договор из адресной книги — подсказка на шаге договора
+	SavedContract string `json:",omitempty"`
+	// кто нашёл: companylookup.ProviderEGRUL и т.п.; пусто — старый черновик (Rusprofile)
+	LookupSource string `json:",omitempty"`
+
+	// заявка пришла вне окна приёма: оформляется этим рабочим днём (unix, полночь по Москве)
+	QueuedFor int64 `json:",omitempty"`
+
+	// ✅ исправление отклонённой заявки: её id, номер счёта (сохраняем его) и причина правки
+	ResubmitOf        int64  `json:",omitempty"`
+	ResubmitInvoiceNo int64  `json:",omitempty"`
+	RejectReason      string `json:",omitempty"`
+}
+
+// resubmitNote — строки про исправление отклонённой заявки для сводок.
+func resubmitNote(d *applicationDraft) []string {
+	if d.ResubmitOf == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("🔁 Исправление заявки по счёту № %d", d.ResubmitInvoiceNo),
+		"Причина правки: " + nz(d.RejectReason),
+		"",
+	}
+}
+
+type userAppState struct {
+	Stage       appStage
+	ReturnStage appStage
+	Draft       applicationDraft
+	// временно храним текущую позицию пока пользователь заполняет шаги
+	CurItem appItem
+	// когда состояние последний раз менялось (для TTL брошенных черновиков)
+	UpdatedAt time.Time
+
+	// ✅ правка из экрана проверки: после шага возвращаемся к проверке, а не идём дальше по мастеру
+	Reviewing bool
+	EditItem  int    // индекс позиции в Draft.Items
+	EditField string // itemFieldName, itemFieldQty, ...
+
+	// ✅ последний отправленный предпросмотр счёта: черновик не менялся — пересылаем по file_id, без soffice
+	PreviewKey    string `json:",omitempty"`
+	PreviewFileID string `json:",omitempty"`
+}
+
+// lookupTitle — откуда данные по ИНН в черновике.
+func lookupTitle(d *applicationDraft) string {
+	switch d.LookupSource {
+	case "":
+		return companylookup.Title(companylookup.ProviderRusprofile)
+	case counterpartyManualSource:
+		return "справочник навигатора"
+	}
+	return companylookup.Title(d.LookupSource)
+}
+
+// ---------- keyboards ----------
+
+func mainMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnMakeApplication),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func stepControlKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+			tgbotapi.NewKeyboardButton(btnSupport),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func contractKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnSkip),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+			tgbotapi.NewKeyboardButton(btnSupport),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func qtyKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnSkip),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+			tgbotapi.NewKeyboardButton(btnSupport),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func itemsDoneKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnAddItem),
+			tgbotapi.NewKeyboardButton(btnFinishItems),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+			tgbotapi.NewKeyboardButton(btnSupport),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func companyPickerKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(company1),
+			tgbotapi.NewKeyboardButton(company2),
+			tgbotapi.NewKeyboardButton(company3),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+			tgbotapi.NewKeyboardButton(btnSupport),
+		),
+	)
+	kb.ResizeKeyboard = true
+	kb.OneTimeKeyboard = true
+	return kb
+}
+
+func continueKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnContinue),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton(btnCancel),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+// ---------- state helpers ----------
+
+func (b *Bot) getOrCreateState(telegramID int64) *userAppState {
+	b.appMu.Lock()
+	defer b.appMu.Unlock()
+
+	st := b.appByUser[telegramID]
+	if st != nil && st.Stage != stageIdle && time.Since(st.UpdatedAt) > b.cfg.Bot3DraftTTL {
+		st = nil
+	}
+	if st == nil {
+		st = b.loadState(telegramID)
+		b.appByUser[telegramID] = st
+	}
+	return st
+}
+
+// loadState поднимает черновик из БД (например, после рестарта бота).
+// Просроченный или битый черновик удаляем и начинаем с idle.
+func (b *Bot) loadState(telegramID int64) *userAppState {
+	idle := &userAppState{Stage: stageIdle}
+
+	raw, updatedAt, ok, err := storage.GetAppDraft(b.db, telegramID)
+	if err != nil {
+		b.logf("GetAppDraft error: %v", err)
+		return idle
+	}
+	if !ok {
+		return idle
+	}
+	if time.Since(updatedAt) > b.cfg.Bot3DraftTTL {
+		_ = storage.DeleteAppDraft(b.db, telegramID)
+		return idle
+	}
+
+	var st userAppState
+	if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		b.logf("bad app draft for %d: %v", telegramID, err)
+		_ = storage.DeleteAppDraft(b.db, telegramID)
+		return idle
+	}
+	st.UpdatedAt = updatedAt
+	return &st
+}
+
+// saveState сохраняет состояние в БД после обработки сообщения.
+// Если состояние уже сброшено через clearState — ничего не делаем.
+func (b *Bot) saveState(telegramID int64, st *userAppState) {
+	b.appMu.Lock()
+	current := b.appByUser[telegramID]
+	b.appMu.Unlock()
+	if current != st {
+		return
+	}
+
+	if st.Stage == stageIdle {
+		_ = storage.DeleteAppDraft(b.db, telegramID)
+		return
+	}
+
+	st.UpdatedAt = time.Now()
+	raw, err := json.Marshal(st)
+	if err != nil {
+		b.logf("marshal app draft error: %v", err)
+		return
+	}
+	if err := storage.SaveAppDraft(b.db, telegramID, string(raw)); err != nil {
+		b.logf("SaveAppDraft error: %v", err)
+	}
+}
+
+func (b *Bot) clearState(telegramID int64) {
+	b.appMu.Lock()
+	delete(b.appByUser, telegramID)
+	b.appMu.Unlock()
+
+	if err := storage.DeleteAppDraft(b.db, telegramID); err != nil {
+		b.logf("DeleteAppDraft error: %v", err)
+	}
+}
+
+// PurgeExpiredDrafts удаляет из БД черновики заявок старше Bot3DraftTTL.
+func (b *Bot) PurgeExpiredDrafts() {
+	n, err := storage.PurgeAppDraftsOlderThan(b.db, time.Now().Add(-b.cfg.Bot3DraftTTL))
+	if err != nil {
+		b.logf("PurgeAppDraftsOlderThan error: %v", err)
+		return
+	}
+	if n > 0 {
+		b.logf("purged %d expired app drafts", n)
+	}
+}
+
+// offerResume: после /start предлагаем вернуться к сохранённому черновику.
+// Используем ту же паузу, что и при вопросе в поддержку.
+func (b *Bot) offerResume(chatID int64, st *userAppState) {
+	switch st.Stage {
+	case stageAwaitContinue:
+		// уже на паузе — ReturnStage выставлен
+	case stageSupportQuestion:
+		// ReturnStage уже указывает на шаг заявки
+		st.Stage = stageAwaitContinue
+	default:
+		st.ReturnStage = st.Stage
+		st.Stage = stageAwaitContinue
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"У вас есть незавершённая заявка (остановились на шаге «%s»).\nНажмите «Продолжить», чтобы вернуться к нему, или «Отмена», чтобы удалить черновик.",
+		stageTitle(st.ReturnStage),
+	))
+	msg.ReplyMarkup = continueKeyboard()
+	_, _ = b.send(msg)
+}
+
+func stageTitle(s appStage) string {
+	switch s {
+	case stageChooseCompany:
+		return "выбор компании"
+	case stageAwaitINN:
+		return "ИНН"
+	case stageAwaitLegalName:
+		return "название юр. лица"
+	case stageAwaitItemName:
+		return "наименование позиции"
+	case stageAwaitItemQty:
+		return "количество"
+	case stageAwaitItemUnit:
+		return "единица измерения"
+	case stageAwaitItemUnitPrice:
+		return "цена за единицу"
+	case stageAwaitItemLineTotal:
+		return "сумма по позиции"
+	case stageAskMoreItems:
+		return "список позиций"
+	case stageAwaitContract:
+		return "номер договора"
+	case stageReview:
+		return "проверка заявки"
+	case stageEditItemField:
+		return "исправление позиции"
+	case stageConfirm:
+		return "подтверждение отправки"
+	case stageAwaitKPP:
+		return "КПП"
+	default:
+		return "заявка"
+	}
+}
+
+// ---------- prompts ----------
+
+func (b *Bot) promptForStage(chatID int64, st *userAppState) {
+	switch st.Stage {
+	case stageChooseCompany:
+		msg := tgbotapi.NewMessage(chatID, "Выберите компанию:")
+		msg.ReplyMarkup = companyPickerKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitINN:
+		msg := tgbotapi.NewMessage(chatID, "Введите ИНН:")
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+		// ✅ в личке chatID = telegram id пользователя
+		b.sendSavedBuyers(chatID, chatID)
+
+	case stageAwaitKPP:
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Реестр не вернул КПП для ИНН %s. Введите КПП (9 символов) или нажмите «Пропуск», если его нет под рукой:", st.Draft.INN))
+		msg.ReplyMarkup = contractKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitLegalName:
+		q := "Введите название юр. лица:"
+		if isSoleProprietorINN(st.Draft.INN) {
+			q = "ИНН из 12 цифр — это ИП. Введите ФИО предпринимателя (например: Иванов Иван Иванович):"
+		}
+		msg := tgbotapi.NewMessage(chatID, q)
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitItemName:
+		n := len(st.Draft.Items) + 1
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Введите наименование позиции №%d:", n))
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitItemQty:
+		msg := tgbotapi.NewMessage(chatID, "Введите количество (число). Можно «Пропуск» = 1:")
+		msg.ReplyMarkup = qtyKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitItemUnit:
+		msg := tgbotapi.NewMessage(chatID, "Введите единицу измерения (например: шт, кг, м, усл):")
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitItemUnitPrice:
+		msg := tgbotapi.NewMessage(chatID, "Введите цену за единицу (например: 1000 или 1 000):")
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitItemLineTotal:
+		var q string
+		if st.CurItem.Qty == 1 {
+			q = "Введите итоговую сумму по позиции (она же цена за единицу, т.к. количество = 1):"
+		} else {
+			q = "Введите ОБЩУЮ стоимость по позиции (итого по строке). Это НЕ цена за единицу:"
+		}
+		msg := tgbotapi.NewMessage(chatID, q)
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAskMoreItems:
+		msg := tgbotapi.NewMessage(chatID, "Добавить ещё позицию или завершить список?")
+		msg.ReplyMarkup = itemsDoneKeyboard()
+		_, _ = b.send(msg)
+
+	case stageAwaitContract:
+		msg := tgbotapi.NewMessage(chatID, "Введите номер договора:")
+		kb := contractKeyboard()
+		// покупатель из адресной книги — прошлый договор одной кнопкой
+		if c := strings.TrimSpace(st.Draft.SavedContract); c != "" && c != "0" && c != st.Draft.Contract {
+			kb.Keyboard = append([][]tgbotapi.KeyboardButton{tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(c))}, kb.Keyboard...)
+		}
+		msg.ReplyMarkup = kb
+		_, _ = b.send(msg)
+
+	case stageReview:
+		b.sendReview(chatID, st)
+
+	case stageEditItemField:
+		b.promptItemField(chatID, st)
+
+	case stageConfirm:
+		// после паузы/рестарта — только сводка, предпросмотр заново по «Изменить» → «Готово»
+		b.sendConfirmSummary(chatID, st)
+	}
+}
+
+// afterLegalName — название принято: дальше к позициям или обратно к проверке.
+func (b *Bot) afterLegalName(chatID int64, st *userAppState) {
+	if st.Reviewing {
+		b.goReview(chatID, st)
+		return
+	}
+	st.Stage = stageAwaitItemName
+	st.CurItem = appItem{}
+	b.promptForStage(chatID, st)
+}
+
+// ---------- main handler ----------
+
+// handleApplicationMessage: личка bot3 — мастер заявки поверх обычной переписки с навигатором.
+func (b *Bot) handleApplicationMessage(ctx context.Context, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	st := b.getOrCreateState(int64(m.From.ID))
+	defer b.saveState(int64(m.From.ID), st)
+
+	// ✅ ПАУЗА: пользователь может свободно писать навигатору
+	if st.Stage == stageAwaitContinue {
+		if txt == btnCancel {
+			b.clearState(int64(m.From.ID))
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Заявка отменена.")
+			msg.ReplyMarkup = mainMenuKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		if txt == btnContinue {
+			st.Stage = st.ReturnStage
+			st.ReturnStage = stageIdle
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+
+		// любое другое сообщение/файл/фото — отправляем навигатору, НЕ ругаемся
+		if txt != "" || m.Document != nil || len(m.Photo) > 0 {
+			header := "От: " + UserRef(m.From)
+			b.sendHeaderAndMap(b.profile.NavigatorChatID, header, m.Chat.ID, m.MessageID)
+			b.forwardAndMap(b.profile.NavigatorChatID, m.Chat.ID, m.MessageID, m.Chat.ID, m.MessageID)
+
+			// ✅ помечаем как support, чтобы ответ пришёл reply (если навигатор ответит reply в своём чате)
+			b.markSupportQuestion(m.Chat.ID, m.MessageID)
+		}
+
+		// ничего пользователю не пишем, чтобы не мешать диалогу
+		return
+	}
+
+	// кнопки во время заявки
+	if st.Stage != stageIdle {
+		if txt == btnCancel {
+			b.clearState(int64(m.From.ID))
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Заявка отменена.")
+			msg.ReplyMarkup = mainMenuKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		if txt == btnSupport {
+			st.ReturnStage = st.Stage
+			st.Stage = stageSupportQuestion
+
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Напишите свой вопрос:")
+			msg.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+	}
+
+	// старт заявки
+	if st.Stage == stageIdle && txt == btnMakeApplication {
+		// ✅ выходной/праздник, приём закончен или сокращённый день — предупреждаем сразу
+		if !b.intakeAllowsStart(m.Chat.ID, time.Now()) {
+			return
+		}
+		st.Stage = stageChooseCompany
+		st.Draft = applicationDraft{}
+		b.promptForStage(m.Chat.ID, st)
+		return
+	}
+
+	// поддержка: отправили вопрос → ставим на паузу
+	if st.Stage == stageSupportQuestion {
+		if txt == "" && m.Document == nil && len(m.Photo) == 0 {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Напишите текст или отправьте файл/фото."))
+			return
+		}
+
+		header := "От: " + UserRef(m.From)
+		b.sendHeaderAndMap(b.profile.NavigatorChatID, header, m.Chat.ID, m.MessageID)
+		b.forwardAndMap(b.profile.NavigatorChatID, m.Chat.ID, m.MessageID, m.Chat.ID, m.MessageID)
+
+		// ✅ помечаем этот вопрос как support
+		b.markSupportQuestion(m.Chat.ID, m.MessageID)
+
+		// ✅ пауза
+		st.Stage = stageAwaitContinue
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Вопрос отправлен. Заполнение заявки поставлено на паузу.\nНажмите «Продолжить», чтобы продолжить с того же шага.")
+		msg.ReplyMarkup = continueKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	// обычный режим вне заявки — как раньше: просто в поддержку
+	if st.Stage == stageIdle {
+		b.routeUserMessage(m)
+		return
+	}
+
+	// ----- шаги заявки -----
+	switch st.Stage {
+	case stageChooseCompany:
+		choice := strings.TrimSpace(txt)
+		if choice != company1 && choice != company2 && choice != company3 {
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Пожалуйста, выберите компанию кнопкой снизу.")
+			msg.ReplyMarkup = companyPickerKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+
+		st.Draft.Company = choice
+		st.Stage = stageAwaitINN
+
+		// убираем клаву выбора компании
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Введите ИНН:")
+		msg.ReplyMarkup = stepControlKeyboard()
+		_, _ = b.send(msg)
+		return
+
+	case stageAwaitINN:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		// ✅ опечатку в ИНН ловим до похода в реестр
+		inn, ierr := normalizeINN(txt)
+		if ierr != nil {
+			msg := tgbotapi.NewMessage(m.Chat.ID, "⚠️ "+ierr.Error()+"\nВведите ИНН ещё раз:")
+			msg.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+
+		b.applyINN(ctx, m.Chat.ID, st, inn, nil)
+		return
+
+	case stageAwaitKPP:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		if txt != btnSkip {
+			kpp, kerr := normalizeKPP(txt)
+			if kerr != nil {
+				msg := tgbotapi.NewMessage(m.Chat.ID, "⚠️ "+kerr.Error()+"\nВведите КПП ещё раз или нажмите «Пропуск»:")
+				msg.ReplyMarkup = contractKeyboard()
+				_, _ = b.send(msg)
+				return
+			}
+			st.Draft.KPP = kpp
+		}
+
+		st.Stage = stageAwaitLegalName
+		b.promptForStage(m.Chat.ID, st)
+		return
+
+	case stageAwaitLegalName:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+
+		// если есть имя из реестра — проверяем похожесть (кавычки, дефисы, ОПФ и мелкие опечатки не мешают)
+		if st.Draft.RusName != "" && !orgNamesMatch(txt, st.Draft.RusName) {
+			msg := tgbotapi.NewMessage(
+				m.Chat.ID,
+				fmt.Sprintf(
+					"По ИНН %s в реестре (%s) организация указана как:\n%s\n\nНажмите кнопку, чтобы взять это название, или введите название юридического лица ещё раз.",
+					st.Draft.INN,
+					lookupTitle(&st.Draft),
+					st.Draft.RusName,
+				),
+			)
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("✅ Взять название из реестра", "appd:usereg"),
+				),
+			)
+			_, _ = b.send(msg)
+			return
+		}
+
+		// ✅ храним ввод пользователя для текста/сообщений
+		st.Draft.LegalName = txt
+		b.afterLegalName(m.Chat.ID, st)
+		return
+
+	case stageAwaitItemName:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		st.CurItem = appItem{Name: txt, Qty: 1}
+		st.Stage = stageAwaitItemQty
+		b.promptForStage(m.Chat.ID, st)
+		return
+
+	case stageAwaitItemQty:
+		if txt == btnSkip {
+			st.CurItem.Qty = 1
+			st.Stage = stageAwaitItemUnit
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		q, qerr := strconv.ParseInt(strings.TrimSpace(txt), 10, 64)
+		if qerr != nil || q <= 0 {
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Введите количество числом (например: 1, 2, 10) или нажмите «Пропуск».")
+			msg.ReplyMarkup = qtyKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		st.CurItem.Qty = q
+		st.Stage = stageAwaitItemUnit
+		b.promptForStage(m.Chat.ID, st)
+		return
+
+	case stageAwaitItemUnit:
+		u := strings.TrimSpace(txt)
+		if u == "" || u == btnSkip {
+			// если пользователь нажал пропуск — оставим пусто, в счёте подставим "шт"
+			u = ""
+		}
+		st.CurItem.Unit = u
+
+		// если количество 1 — пропускаем ввод цены за единицу, спрашиваем только итог
+		if st.CurItem.Qty == 1 {
+			st.Stage = stageAwaitItemLineTotal
+		} else {
+			st.Stage = stageAwaitItemUnitPrice
+		}
+
+		b.promptForStage(m.Chat.ID, st)
+		return
+
+	case stageAwaitItemUnitPrice:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		p, perr := parseMoney(txt)
+		if perr != nil {
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Не смог распознать цену. Пример: 1000 или 1 000")
+			msg.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		st.CurItem.UnitPrice = p
+		st.Stage = stageAwaitItemLineTotal
+		b.promptForStage(m.Chat.ID, st)
+		return
+
+	case stageAwaitItemLineTotal:
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		s, serr := parseMoney(txt)
+		if serr != nil {
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Не смог распознать сумму. Пример: 1000000 или 1 000 000")
+			msg.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		// qty==1: введённая сумма = и цена за единицу, и итог
+		if st.CurItem.Qty == 1 {
+			st.CurItem.UnitPrice = s
+			st.CurItem.Total = s
+			st.Draft.Items = append(st.Draft.Items, st.CurItem)
+			st.CurItem = appItem{}
+			b.afterItemAdded(m.Chat.ID, st)
+			return
+		}
+
+		// qty>1: проверка корректности суммы
+		expected := float64(st.CurItem.Qty) * st.CurItem.UnitPrice
+
+		if math.Abs(expected-s) > 0.0001 {
+			// 1️⃣ первое сообщение — ТОЛЬКО про ошибку
+			_, _ = b.send(tgbotapi.NewMessage(
+				m.Chat.ID,
+				fmt.Sprintf(
+					"Сумма не сходится: %d × %.2f = %.2f, а вы ввели %.2f.",
+					st.CurItem.Qty, st.CurItem.UnitPrice, expected, s,
+				),
+			))
+
+			// 2️⃣ второе сообщение — инструкция + клавиатура
+			msg2 := tgbotapi.NewMessage(
+				m.Chat.ID,
+				"Введите заново цену за единицу и итог по этой позиции.",
+			)
+			msg2.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg2)
+
+			// возвращаемся на ввод цены
+			st.CurItem.Total = 0
+			st.Stage = stageAwaitItemUnitPrice
+			return
+		}
+
+		st.CurItem.Total = s
+		st.Draft.Items = append(st.Draft.Items, st.CurItem)
+		st.CurItem = appItem{}
+		b.afterItemAdded(m.Chat.ID, st)
+		return
+
+	case stageAskMoreItems:
+		switch txt {
+		case btnAddItem:
+			st.Stage = stageAwaitItemName
+			b.promptForStage(m.Chat.ID, st)
+			return
+		case btnFinishItems:
+			if len(st.Draft.Items) == 0 {
+				st.Stage = stageAwaitItemName
+				b.promptForStage(m.Chat.ID, st)
+				return
+			}
+			st.Stage = stageAwaitContract
+			b.promptForStage(m.Chat.ID, st)
+			return
+		default:
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Выберите вариант кнопкой снизу.")
+			msg.ReplyMarkup = itemsDoneKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+
+	case stageAwaitContract:
+		if txt == btnSkip {
+			st.Draft.Contract = "0"
+			b.goConfirm(ctx, m.Chat.ID, st)
+			return
+		}
+		if txt == "" {
+			b.promptForStage(m.Chat.ID, st)
+			return
+		}
+		st.Draft.Contract = txt
+		b.goConfirm(ctx, m.Chat.ID, st)
+		return
+
+	case stageReview:
+		// заявка уже заполнена — дальше только кнопки под сводкой
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Проверьте заявку и выберите действие кнопками под ней."))
+		b.sendReview(m.Chat.ID, st)
+		return
+
+	case stageConfirm:
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Нажмите «📤 Отправить» или «✏️ Изменить» под сводкой."))
+		b.sendConfirmSummary(m.Chat.ID, st)
+		return
+
+	case stageEditItemField:
+		if txt == "" {
+			b.promptItemField(m.Chat.ID, st)
+			return
+		}
+		if st.EditItem < 0 || st.EditItem >= len(st.Draft.Items) {
+			b.goReview(m.Chat.ID, st)
+			return
+		}
+		if errText := applyItemField(&st.Draft.Items[st.EditItem], st.EditField, txt); errText != "" {
+			msg := tgbotapi.NewMessage(m.Chat.ID, errText)
+			msg.ReplyMarkup = stepControlKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+		b.goReview(m.Chat.ID, st)
+		return
+	}
+}
+
+// sendForApproval: chatID — личка пользователя, userMessageID — к чему привязать reply из чата подтверждения.
+func (b *Bot) sendForApproval(ctx context.Context, chatID int64, from *tgbotapi.User, userMessageID int, st *userAppState) {
+	user := UserRef(from)
+
+	// ✅ окно приёма проверяем в момент отправки: заявку могли начать до дедлайна
+	now := time.Now()
+	intake, queuedFor, ok := b.intakeOnSubmit(now)
+	if !ok {
+		// черновик не теряем: «Продолжить» вернёт к подтверждению
+		st.ReturnStage = stageConfirm
+		st.Stage = stageAwaitContinue
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"⛔ %s\nЗаявка не отправлена, черновик сохранён. Приём откроется %s в %s — нажмите «Продолжить» и отправьте заявку.",
+			intake.closedReason(now), formatDayRu(intake.OpensAt), intake.OpensAt.Format("15:04"),
+		))
+		msg.ReplyMarkup = continueKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+	st.Draft.QueuedFor = 0
+	if !queuedFor.IsZero() {
+		st.Draft.QueuedFor = queuedFor.Unix()
+	}
+
+	// считаем итоговую сумму
+	total := 0.0
+	for _, it := range st.Draft.Items {
+		total += it.Total
+	}
+	st.Draft.TotalSum = total
+
+	pos := []string{"Позиции:"}
+	for i, it := range st.Draft.Items {
+		pos = append(pos, fmt.Sprintf("%d) %s; кол-во=%d; ед=%s; цена=%.2f; итого=%.2f", i+1, it.Name, it.Qty, it.Unit, it.UnitPrice, it.Total))
+	}
+
+	parts := []string{
+		"📝 Заявка на подтверждение",
+	}
+	if st.Draft.ResubmitOf != 0 {
+		parts = []string{
+			fmt.Sprintf("🔁 Исправленная заявка (счёт № %d был отправлен на правку)", st.Draft.ResubmitInvoiceNo),
+			"Причина правки: " + nz(st.Draft.RejectReason),
+		}
+	}
+	if !queuedFor.IsZero() {
+		parts = append(parts, "📅 Следующий рабочий день: поступила вне времени приёма, оформлена датой "+formatDayRu(queuedFor))
+	}
+	parts = append(parts,
+		fmt.Sprintf("От: %s", user),
+		fmt.Sprintf("Компания: %s", st.Draft.Company),
+		fmt.Sprintf("ИНН: %s", st.Draft.INN),
+		fmt.Sprintf("Юр.лицо (ввод): %s", st.Draft.LegalName),
+		strings.Join(pos, "\n"),
+		fmt.Sprintf("Сумма итого: %.2f", st.Draft.TotalSum),
+		fmt.Sprintf("Договор: %s", st.Draft.Contract),
+		"",
+		"Данные реестра ("+lookupTitle(&st.Draft)+"):",
+		kppLine(&st.Draft),
+		fmt.Sprintf("Название: %s", nz(st.Draft.RusName)),
+		fmt.Sprintf("Адрес: %s", nz(st.Draft.RusAddress)),
+	)
+	if strings.TrimSpace(st.Draft.RusErr) != "" {
+		parts = append(parts, "", "⚠️ Ошибка парсинга/получения:", st.Draft.RusErr)
+	}
+
+	text := strings.Join(parts, "\n")
+
+	if err := b.SendApplicationToApproval(ctx, chatID, userMessageID, text, st.Draft); err != nil {
+		b.logf("SendApplicationToApproval error: %v", err)
+		// ✅ черновик не трогаем: та же сводка снова доступна для отправки
+		st.Stage = stageConfirm
+		msg := tgbotapi.NewMessage(chatID, "❌ Заявка не отправлена: "+err.Error()+"\nЧерновик сохранён — можно попробовать ещё раз.")
+		msg.ReplyMarkup = confirmKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+	b.rememberBuyer(from.ID, &st.Draft)
+
+	done := "Заявка отправлена на подтверждение ✅"
+	if !queuedFor.IsZero() {
+		done += fmt.Sprintf("\n%s Заявка будет оформлена рабочим днём %s.", intake.closedReason(now), formatDayRu(queuedFor))
+	}
+	msg := tgbotapi.NewMessage(chatID, done)
+	msg.ReplyMarkup = mainMenuKeyboard()
+	_, _ = b.send(msg)
+
+	b.clearState(from.ID)
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// сколько дней максимум можно указать для «активных за N дней»
+const maxAudienceDays = 365
+
+// askBroadcastAudience: после шаблона рассылки — выбор, кому её отправлять.
+func (b *Bot) askBroadcastAudience(chatID int64) {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Все авторизованные", "bc_aud:"+storage.AudienceAllowed),
+		),
+	}
+	if b.profile.CompanyPicker {
+		var row []tgbotapi.InlineKeyboardButton
+		for company := 1; company <= len(b.profile.AccountingChatIDs); company++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("🏢 "+CompanyName(company),
+				fmt.Sprintf("bc_aud:%s:%d", storage.AudienceCompany, company)))
+			if len(row) == 2 {
+				rows = append(rows, row)
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🕒 Активные за N дней", "bc_aud:"+storage.AudienceActive),
+			tgbotapi.NewInlineKeyboardButtonData("📋 Список", "bc_aud:"+storage.AudienceList),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", "broadcast_cancel"),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, "Кому отправить рассылку?")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+// handleAudienceCallback: кнопки bc_aud:* (сессия уже захвачена вызывающим).
+func (b *Bot) handleAudienceCallback(s *navBroadcastState, chatID int64, data string) {
+	if s.Payload == nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Рассылка не найдена. Нажмите «📨 Рассылка» заново."))
+		return
+	}
+
+	spec := strings.TrimPrefix(data, "bc_aud:")
+	switch spec {
+	case "change":
+		s.Stage = bStageIdle
+		s.Audience = nil
+		b.askBroadcastAudience(chatID)
+		return
+
+	case storage.AudienceActive:
+		s.Stage = bStageAwaitAudienceDays
+		msg := tgbotapi.NewMessage(chatID, "За сколько последних дней пользователь должен был писать боту? Введите число, например 30.\nОтмена: «❌ Отмена».")
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+		return
+
+	case storage.AudienceList:
+		s.Stage = bStageAwaitAudienceList
+		msg := tgbotapi.NewMessage(chatID, "Пришлите список получателей: @username или telegram id через пробел, запятую или с новой строки.\nОтмена: «❌ Отмена».")
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	a, err := storage.ParseAudience(spec)
+	if err != nil || a.Kind == storage.AudienceAll {
+		return
+	}
+	s.Audience = &a
+	b.sendBroadcastPreview(s, chatID)
+}
+
+func (b *Bot) handleAudienceDaysInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	days, err := strconv.Atoi(txt)
+	if err != nil || days <= 0 || days > maxAudienceDays {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, fmt.Sprintf("Введите число дней от 1 до %d.", maxAudienceDays)))
+		return
+	}
+
+	s.Stage = bStageIdle
+	s.Audience = &storage.Audience{Kind: storage.AudienceActive, Days: days}
+	b.sendBroadcastPreview(s, m.Chat.ID)
+}
+
+func (b *Bot) handleAudienceListInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	refs, bad := storage.ParseUserRefs(txt)
+	if len(bad) > 0 {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не понял: "+strings.Join(bad, ", ")+"\nНужны @username или telegram id (числа). Пришлите список ещё раз."))
+		return
+	}
+	if len(refs) == 0 {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Список пуст. Пришлите @username или telegram id."))
+		return
+	}
+
+	s.Stage = bStageIdle
+	s.Audience = &storage.Audience{Kind: storage.AudienceList, Refs: refs}
+	b.sendBroadcastPreview(s, m.Chat.ID)
+}
+
+// sendBroadcastPreview: сколько человек получит рассылку + кнопки отправки.
+func (b *Bot) sendBroadcastPreview(s *navBroadcastState, chatID int64) {
+	chatIDs, missing, err := b.audienceChatIDs(*s.Audience)
+	if err != nil {
+		b.logf("audienceChatIDs error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось посчитать получателей (ошибка БД)."))
+		return
+	}
+
+	lines := []string{
+		"Аудитория: " + audienceTitle(*s.Audience),
+		fmt.Sprintf("Получателей: %d", len(chatIDs)),
+	}
+	if len(missing) > 0 {
+		const maxShown = 20
+		shown := missing
+		if len(shown) > maxShown {
+			shown = shown[:maxShown]
+		}
+		line := fmt.Sprintf("Не найдены или не подходят (%d): %s", len(missing), strings.Join(shown, ", "))
+		if len(missing) > maxShown {
+			line += ", …"
+		}
+		lines = append(lines, line)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(chatIDs) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📤 Отправить сейчас", "broadcast_send_now"),
+			tgbotapi.NewInlineKeyboardButtonData("⏰ Запланировать", "broadcast_schedule"),
+		), tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Регулярно", "broadcast_recurring"),
+		))
+	} else {
+		lines = append(lines, "Отправлять некому — выберите другую аудиторию.")
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("👥 Другая аудитория", "bc_aud:change"),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", "broadcast_cancel"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+// audienceChatIDs — получатели рассылки без служебных чатов бота.
+func (b *Bot) audienceChatIDs(a storage.Audience) ([]int64, []string, error) {
+	all, missing, err := storage.ListAudienceChatIDs(b.db, b.profile.Name, a, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var chatIDs []int64
+	for _, cid := range all {
+		if !b.profile.isServiceChat(cid) {
+			chatIDs = append(chatIDs, cid)
+		}
+	}
+	return chatIDs, missing, nil
+}
+
+func audienceTitle(a storage.Audience) string {
+	switch a.Kind {
+	case storage.AudienceAllowed:
+		return "все авторизованные"
+	case storage.AudienceCompany:
+		return "авторизованные, " + CompanyName(a.Company)
+	case storage.AudienceActive:
+		return fmt.Sprintf("активные за %d дн.", a.Days)
+	case storage.AudienceList:
+		text := strings.Join(a.Refs, ", ")
+		r := []rune(text)
+		if len(r) > 100 {
+			text = string(r[:100]) + "…"
+		}
+		return fmt.Sprintf("список (%d): %s", len(a.Refs), text)
+	default:
+		return "все в базе"
+	}
+}
+
+// audienceTitleSpec — то же для строки из БД.
+func audienceTitleSpec(spec string) string {
+	a, err := storage.ParseAudience(spec)
+	if err != nil {
+		return spec
+	}
+	return audienceTitle(a)
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// sendBroadcastReport: /report — последние рассылки, /report N — сводка по рассылке N.
+func (b *Bot) sendBroadcastReport(chatID int64, args string) {
+	args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if args == "" {
+		b.sendRecentBroadcasts(chatID)
+		return
+	}
+
+	id, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || id <= 0 {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Формат: /report или /report <номер рассылки>"))
+		return
+	}
+	b.sendBroadcastSummary(chatID, id)
+}
+
+func (b *Bot) sendRecentBroadcasts(chatID int64) {
+	list, err := storage.ListRecentBroadcasts(b.db, b.profile.Name, 10)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить список рассылок (ошибка БД)."))
+		return
+	}
+	if len(list) == 0 {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Рассылок ещё не было."))
+		return
+	}
+
+	loc := moscowLocation()
+	lines := []string{"📊 Последние рассылки:"}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, bc := range list {
+		lines = append(lines, fmt.Sprintf("#%d — %s — ✅ %d / ❌ %d из %d",
+			bc.ID, bc.StartedAt.In(loc).Format("02.01.2006 15:04"), bc.Sent, bc.Failed, bc.Total))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📊 Отчёт #%d", bc.ID), fmt.Sprintf("bc_report:%d", bc.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) sendBroadcastSummary(chatID int64, id int64) {
+	bc, ok, err := storage.GetBroadcast(b.db, b.profile.Name, id)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить рассылку (ошибка БД)."))
+		return
+	}
+	if !ok {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Рассылка #%d не найдена.", id)))
+		return
+	}
+
+	loc := moscowLocation()
+	lines := []string{
+		fmt.Sprintf("📊 Рассылка #%d", bc.ID),
+		"Начата: " + bc.StartedAt.In(loc).Format("02.01.2006 15:04"),
+	}
+	if bc.FinishedAt.IsZero() {
+		lines = append(lines, "Завершена: — (прервана или ещё идёт)")
+	} else {
+		lines = append(lines, "Завершена: "+bc.FinishedAt.In(loc).Format("02.01.2006 15:04"))
+	}
+	if bc.ScheduledID != 0 {
+		lines = append(lines, fmt.Sprintf("По расписанию: #%d", bc.ScheduledID))
+	}
+	if bc.RecurringID != 0 {
+		lines = append(lines, fmt.Sprintf("Регулярная: #%d", bc.RecurringID))
+	}
+	lines = append(lines,
+		"Аудитория: "+audienceTitleSpec(bc.Audience),
+		fmt.Sprintf("Получателей: %d", bc.Total),
+		fmt.Sprintf("✅ Доставлено: %d", bc.Sent),
+		fmt.Sprintf("❌ Не доставлено: %d", bc.Failed),
+		"Содержимое: "+scheduledSummary(storage.ScheduledBroadcast{
+			Text:           bc.Text,
+			DocumentFileID: bc.DocumentFileID,
+			PhotoFileID:    bc.PhotoFileID,
+			MessageIDs:     bc.MessageIDs,
+			Kind:           bc.Kind,
+		}),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	if bc.Failed > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📥 Не доставлено (CSV)", fmt.Sprintf("bc_failed_csv:%d", bc.ID)),
+		))
+	}
+	_, _ = b.send(msg)
+}
+
+// sendFailedDeliveriesCSV выгружает получателей, которым рассылка не дошла.
+func (b *Bot) sendFailedDeliveriesCSV(chatID int64, id int64) {
+	if _, ok, err := storage.GetBroadcast(b.db, b.profile.Name, id); err != nil || !ok {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Рассылка #%d не найдена.", id)))
+		return
+	}
+
+	list, err := storage.ListFailedDeliveries(b.db, id)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить список (ошибка БД)."))
+		return
+	}
+	if len(list) == 0 {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("В рассылке #%d все сообщения доставлены.", id)))
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff") // BOM — чтобы Excel открыл кириллицу
+	w := csv.NewWriter(&buf)
+	w.Comma = ';' // русский Excel ждёт ;
+	_ = w.Write([]string{"chat_id", "username", "error", "time"})
+
+	loc := moscowLocation()
+	for _, d := range list {
+		username := ""
+		if d.Username != "" {
+			username = "@" + strings.TrimPrefix(d.Username, "@")
+		}
+		_ = w.Write([]string{
+			strconv.FormatInt(d.ChatID, 10),
+			username,
+			d.Error,
+			d.SentAt.In(loc).Format("02.01.2006 15:04:05"),
+		})
+	}
+	w.Flush()
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("broadcast_%d_failed.csv", id),
+		Bytes: buf.Bytes(),
+	})
+	doc.Caption = fmt.Sprintf("Рассылка #%d: не доставлено %d", id, len(list))
+	if _, err := b.send(doc); err != nil {
+		b.logf("send failed deliveries csv error: %v", err)
+	}
+}
+
+func (b *Bot) handleBroadcastReportCallback(cq *tgbotapi.CallbackQuery) {
+	data := cq.Data
+	switch {
+	case strings.HasPrefix(data, "bc_report:"):
+		if id, err := strconv.ParseInt(strings.TrimPrefix(data, "bc_report:"), 10, 64); err == nil {
+			b.sendBroadcastSummary(cq.Message.Chat.ID, id)
+		}
+	case strings.HasPrefix(data, "bc_failed_csv:"):
+		if id, err := strconv.ParseInt(strings.TrimPrefix(data, "bc_failed_csv:"), 10, 64); err == nil {
+			b.sendFailedDeliveriesCSV(cq.Message.Chat.ID, id)
+		}
+	}
+}
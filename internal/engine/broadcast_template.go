@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// сколько ждём следующую часть альбома, прежде чем считать его полученным
+const albumDebounce = 1500 * time.Millisecond
+
+// templateKind: что за сообщение навигатор прислал как шаблон рассылки. "" — разослать нельзя.
+func templateKind(m *tgbotapi.Message) string {
+	switch {
+	case m.MediaGroupID != "":
+		return "album"
+	case len(m.Photo) > 0:
+		return "photo"
+	case m.Document != nil && m.Animation == nil:
+		return "document"
+	case m.Animation != nil:
+		return "animation"
+	case m.Video != nil:
+		return "video"
+	case m.VideoNote != nil:
+		return "video_note"
+	case m.Audio != nil:
+		return "audio"
+	case m.Voice != nil:
+		return "voice"
+	case m.Sticker != nil:
+		return "sticker"
+	case m.Text != "":
+		return "text"
+	default:
+		return ""
+	}
+}
+
+func templateKindLabel(kind string) string {
+	switch kind {
+	case "photo":
+		return "[фото]"
+	case "document":
+		return "[документ]"
+	case "animation":
+		return "[gif]"
+	case "video":
+		return "[видео]"
+	case "video_note":
+		return "[видеосообщение]"
+	case "audio":
+		return "[аудио]"
+	case "voice":
+		return "[голосовое]"
+	case "sticker":
+		return "[стикер]"
+	case "album":
+		return "[альбом]"
+	default:
+		return ""
+	}
+}
+
+func (s *navBroadcastState) stopAlbumTimer() {
+	if s.albumTimer != nil {
+		s.albumTimer.Stop()
+		s.albumTimer = nil
+	}
+}
+
+// waitAlbumEnd перезапускает ожидание: пока приходят части альбома, выбор аудитории не показываем.
+func (b *Bot) waitAlbumEnd(s *navBroadcastState, chatID, userID int64, groupID string) {
+	s.stopAlbumTimer()
+	s.albumTimer = time.AfterFunc(albumDebounce, func() {
+		b.finishAlbumTemplate(chatID, userID, groupID)
+	})
+}
+
+func (b *Bot) finishAlbumTemplate(chatID, userID int64, groupID string) {
+	s, unlock := b.lockNavSession(chatID, userID)
+	defer unlock()
+
+	// за это время рассылку могли отменить или прислать другой шаблон
+	if s.Stage != bStageAwaitTemplate || s.Payload == nil || s.Payload.MediaGroupID != groupID {
+		return
+	}
+
+	s.albumTimer = nil
+	sort.Ints(s.Payload.MessageIDs) // copyMessages требует возрастающий порядок
+	s.Payload.MediaGroupID = ""
+	s.Audience = nil
+	s.Stage = bStageIdle
+
+	b.askBroadcastAudience(chatID)
+}
+
+// copyBroadcastPayload копирует шаблон из чата навигатора получателю.
+// Альбом уходит одним copyMessages, чтобы остался альбомом.
+func (b *Bot) copyBroadcastPayload(ctx context.Context, cid int64, payload *BroadcastPayload) (int, error) {
+	if len(payload.MessageIDs) == 1 {
+		sent, err := b.out.Send(ctx, tgbotapi.NewCopyMessage(cid, payload.SourceChatID, payload.MessageIDs[0]))
+		if err != nil {
+			return 0, err
+		}
+		return sent.MessageID, nil
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", cid)
+	params.AddNonZero64("from_chat_id", payload.SourceChatID)
+	if err := params.AddInterface("message_ids", payload.MessageIDs); err != nil {
+		return 0, err
+	}
+
+	resp, err := b.out.MakeRequest(ctx, cid, "copyMessages", params)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []tgbotapi.MessageID
+	if err := json.Unmarshal(resp.Result, &ids); err != nil {
+		return 0, fmt.Errorf("copyMessages result: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("copyMessages: empty result")
+	}
+	return ids[0].MessageID, nil
+}
@@ -1,14 +1,11 @@
-package tg
+package engine
 
 import (
-	"database/sql"
 	"fmt"
-	"log"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
-	"TGBOT2/internal/config"
 	"TGBOT2/internal/storage"
 )
 
@@ -28,10 +25,10 @@ func companyReplyKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	return kb
 }
 
-func SendCompanyPicker(bot *tgbotapi.BotAPI, chatID int64) {
+func (b *Bot) sendCompanyPicker(chatID int64) {
 	msg := tgbotapi.NewMessage(chatID, "Перед отправкой документов выберите, по какой компании вы хотите их отправить")
 	msg.ReplyMarkup = companyReplyKeyboard()
-	_, _ = bot.Send(msg)
+	_, _ = b.send(msg)
 }
 
 func TryParseCompanyChoice(text string) (int, bool) {
@@ -50,32 +47,23 @@ func TryParseCompanyChoice(text string) (int, bool) {
 	}
 }
 
-func SaveCompanyChoice(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, telegramUserID int64, company int) {
-	// ✅ сохраняем в кэш ВСЕГДА
-
-	// ✅ пытаемся сохранить в БД и логируем ошибку (раньше ты её терял)
-	if err := storage.SetUserCompanyByTelegramID(db, telegramUserID, company); err != nil {
-		log.Printf("SetUserCompanyByTelegramID error: %v", err)
+func (b *Bot) saveCompanyChoice(chatID int64, telegramUserID int64, company int) {
+	// ✅ пытаемся сохранить в БД и логируем ошибку
+	if err := storage.SetUserCompanyByTelegramID(b.db, telegramUserID, company); err != nil {
+		b.logf("SetUserCompanyByTelegramID error: %v", err)
 	}
 
 	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Выбрана: %s\nМожете отправлять файлы", CompanyName(company)))
 	msg.ReplyMarkup = companyReplyKeyboard()
-	_, _ = bot.Send(msg)
+	_, _ = b.send(msg)
 }
 
-func AccountingChatIDByCompany(cfg *config.Config, company int) int64 {
-	switch company {
-	case 1:
-		return cfg.Accounting1ChatID
-	case 2:
-		return cfg.Accounting2ChatID
-	case 3:
-		return cfg.Accounting3ChatID
-	case 4:
-		return cfg.Accounting4ChatID
-	default:
+// accountingChatIDByCompany: бухгалтерская группа для компании из профиля (компания N -> AccountingChatIDs[N-1]).
+func (b *Bot) accountingChatIDByCompany(company int) int64 {
+	if company < 1 || company > len(b.profile.AccountingChatIDs) {
 		return 0
 	}
+	return b.profile.AccountingChatIDs[company-1]
 }
 
 func CompanyName(company int) string {
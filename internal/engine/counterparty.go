@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/companylookup"
+	"TGBOT2/internal/storage"
+)
+
+// источник записи, которую навигатор завёл/исправил руками
+const counterpartyManualSource = "manual"
+
+var reINNDigits = regexp.MustCompile(`^\d{10}(\d{2})?$`)
+
+// lookupCounterparty: сначала кэш counterparties (свежий или исправленный вручную), потом провайдеры.
+// Если провайдеры не ответили — отдаём устаревшую запись из кэша, она лучше, чем ничего.
+func (b *Bot) lookupCounterparty(ctx context.Context, inn string, force bool) (*companylookup.Company, error) {
+	cached, ok, err := storage.GetCounterparty(b.db, inn)
+	if err != nil {
+		b.logf("GetCounterparty error: %v", err)
+		ok = false
+	}
+	// запись без названия неполная (так выглядели ручные правки ИНН, которого не было в кэше) — идём в реестр
+	if ok && !force && cached.Name != "" && (cached.Manual || time.Since(cached.FetchedAt) < b.cfg.CounterpartyTTL) {
+		return counterpartyCompany(cached), nil
+	}
+
+	co, lerr := b.lookup.Lookup(ctx, inn)
+	if lerr != nil {
+		if ok && !force {
+			b.logf("lookup %s failed, using cached data from %s: %v", inn, cached.FetchedAt.Format("02.01.2006"), lerr)
+			return counterpartyCompany(cached), nil
+		}
+		return nil, lerr
+	}
+
+	if err := storage.SaveCounterparty(b.db, &storage.Counterparty{
+		INN:       inn,
+		KPP:       co.KPP,
+		OGRN:      co.OGRN,
+		Name:      co.Name,
+		Address:   co.Address,
+		Source:    co.Source,
+		FetchedAt: time.Now(),
+	}); err != nil {
+		b.logf("SaveCounterparty error: %v", err)
+	}
+	return co, nil
+}
+
+func counterpartyCompany(c *storage.Counterparty) *companylookup.Company {
+	source := c.Source
+	if c.Manual {
+		source = counterpartyManualSource
+	}
+	return &companylookup.Company{
+		INN:     c.INN,
+		KPP:     c.KPP,
+		OGRN:    c.OGRN,
+		Name:    c.Name,
+		Address: c.Address,
+		Source:  source,
+	}
+}
+
+// =====================
+// Панель навигатора: /counterparty
+// =====================
+
+// handleCounterpartyCommand: /counterparty — последние записи, /counterparty <ИНН> — карточка.
+func (b *Bot) handleCounterpartyCommand(chatID int64, arg string) {
+	inn := strings.TrimSpace(arg)
+	if inn == "" {
+		b.sendCounterpartyList(chatID)
+		return
+	}
+	inn, err := normalizeINN(inn)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "⚠️ "+err.Error()+"\nФормат: /counterparty или /counterparty <ИНН>"))
+		return
+	}
+	b.sendCounterpartyCard(chatID, inn)
+}
+
+func (b *Bot) sendCounterpartyList(chatID int64) {
+	const limit = 20
+	list, err := storage.ListCounterparties(b.db, limit)
+	if err != nil {
+		b.logf("ListCounterparties error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить список контрагентов (ошибка БД)."))
+		return
+	}
+	if len(list) == 0 {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Кэш контрагентов пуст. Карточка: /counterparty <ИНН>"))
+		return
+	}
+
+	lines := []string{fmt.Sprintf("Контрагенты (последние %d):", len(list))}
+	for _, c := range list {
+		line := fmt.Sprintf("%s — %s (%s)", c.INN, nz(c.Name), c.FetchedAt.In(moscowLocation()).Format("02.01.2006"))
+		if c.Manual {
+			line += " ✍️"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", "Карточка, обновление и правка: /counterparty <ИНН>")
+	_, _ = b.send(tgbotapi.NewMessage(chatID, strings.Join(lines, "\n")))
+}
+
+func (b *Bot) sendCounterpartyCard(chatID int64, inn string) {
+	c, ok, err := storage.GetCounterparty(b.db, inn)
+	if err != nil {
+		b.logf("GetCounterparty error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить контрагента (ошибка БД)."))
+		return
+	}
+
+	var text string
+	if !ok {
+		text = fmt.Sprintf("ИНН %s: в кэше нет.", inn)
+	} else {
+		source := companylookup.Title(c.Source)
+		if c.Manual {
+			source = "вручную"
+		}
+		lines := []string{
+			"ИНН: " + c.INN,
+			"КПП: " + nz(c.KPP),
+			"ОГРН: " + nz(c.OGRN),
+			"Название: " + nz(c.Name),
+			"Адрес: " + nz(c.Address),
+			"",
+			fmt.Sprintf("Источник: %s, загружено %s", source, c.FetchedAt.In(moscowLocation()).Format("02.01.2006 15:04")),
+		}
+		if c.Manual {
+			lines = append(lines, "✍️ Исправлено вручную — не обновляется само, только кнопкой «Обновить».")
+		} else if age := time.Since(c.FetchedAt); age >= b.cfg.CounterpartyTTL {
+			lines = append(lines, "Запись устарела — при следующей заявке будет загружена заново.")
+		}
+		text = strings.Join(lines, "\n")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить из реестра", "cp_refresh:"+inn),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ КПП", "cp_edit:"+inn+":"+storage.CounterpartyKPP),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Название", "cp_edit:"+inn+":"+storage.CounterpartyName),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Адрес", "cp_edit:"+inn+":"+storage.CounterpartyAddress),
+		),
+	)
+	_, _ = b.send(msg)
+}
+
+// handleCounterpartyRefresh: «🔄 Обновить» — принудительно в реестр, ручная правка сбрасывается.
+func (b *Bot) handleCounterpartyRefresh(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	if cq.From == nil || !b.cfg.ResponderIDs[cq.From.ID] {
+		return
+	}
+	inn := strings.TrimPrefix(cq.Data, "cp_refresh:")
+	if !reINNDigits.MatchString(inn) || b.lookup == nil {
+		return
+	}
+
+	if _, err := b.lookupCounterparty(ctx, inn, true); err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Не удалось обновить ИНН %s: %v", inn, err)))
+		return
+	}
+	b.sendCounterpartyCard(chatID, inn)
+}
+
+// handleCounterpartyEdit: «✏️ …» — ждём новое значение поля от этого сотрудника.
+// Править можно только загруженную запись: ИНН не в кэше — сначала тянем его из реестра.
+func (b *Bot) handleCounterpartyEdit(ctx context.Context, s *navBroadcastState, cq *tgbotapi.CallbackQuery) {
+	if cq.From == nil || !b.cfg.ResponderIDs[cq.From.ID] {
+		return
+	}
+	inn, field, _ := strings.Cut(strings.TrimPrefix(cq.Data, "cp_edit:"), ":")
+	if !reINNDigits.MatchString(inn) {
+		return
+	}
+	title := counterpartyFieldTitle(field)
+	if title == "" {
+		return
+	}
+	if _, ok, err := storage.GetCounterparty(b.db, inn); err != nil || !ok {
+		if err != nil {
+			b.logf("GetCounterparty error: %v", err)
+		}
+		if b.lookup == nil {
+			return
+		}
+		if _, err := b.lookupCounterparty(ctx, inn, true); err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(cq.Message.Chat.ID, fmt.Sprintf("ИНН %s нет в кэше, и реестр его не вернул (%v) — править нечего.", inn, err)))
+			return
+		}
+	}
+
+	s.Stage = bStageAwaitCounterpartyValue
+	s.Payload = nil
+	s.CounterpartyINN = inn
+	s.CounterpartyField = field
+
+	msg := tgbotapi.NewMessage(cq.Message.Chat.ID, fmt.Sprintf("ИНН %s: введите %s.\nОтмена: «❌ Отмена».", inn, title))
+	msg.ReplyMarkup = directMsgKeyboard()
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) handleCounterpartyValueInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+	if txt == "" {
+		return
+	}
+
+	inn, field := s.CounterpartyINN, s.CounterpartyField
+	if field == storage.CounterpartyKPP {
+		kpp, err := normalizeKPP(txt)
+		if err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "⚠️ "+err.Error()+"\nВведите КПП ещё раз или «❌ Отмена»."))
+			return
+		}
+		txt = kpp
+	}
+	updated, err := storage.SetCounterpartyField(b.db, inn, field, txt, m.From.ID)
+	if err != nil {
+		b.logf("SetCounterpartyField error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось сохранить (ошибка БД)."))
+		return
+	}
+	s.Stage = bStageIdle
+	s.CounterpartyINN = ""
+	s.CounterpartyField = ""
+
+	if !updated {
+		msg := tgbotapi.NewMessage(m.Chat.ID, fmt.Sprintf("ИНН %s пропал из кэша — откройте /counterparty %s заново.", inn, inn))
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+	b.logf("counterparty %s: %s corrected by %d", inn, field, m.From.ID)
+
+	msg := tgbotapi.NewMessage(m.Chat.ID, "Сохранено ✅")
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+	b.sendCounterpartyCard(m.Chat.ID, inn)
+}
+
+func counterpartyFieldTitle(field string) string {
+	switch field {
+	case storage.CounterpartyKPP:
+		return "КПП"
+	case storage.CounterpartyName:
+		return "название"
+	case storage.CounterpartyAddress:
+		return "адрес"
+	}
+	return ""
+}
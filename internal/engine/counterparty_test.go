@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"TGBOT2/internal/companylookup"
+	"TGBOT2/internal/config"
+	"TGBOT2/internal/sender"
+	"TGBOT2/internal/storage"
+)
+
+// countingLookup — провайдер-заглушка: считает обращения, отдаёт co или err.
+type countingLookup struct {
+	co    companylookup.Company
+	err   error
+	calls int
+}
+
+func (l *countingLookup) Name() string { return companylookup.ProviderFake }
+
+func (l *countingLookup) Lookup(_ context.Context, inn string) (*companylookup.Company, error) {
+	l.calls++
+	if l.err != nil {
+		return nil, l.err
+	}
+	co := l.co
+	co.INN = inn
+	return &co, nil
+}
+
+func TestLookupCounterpartyCache(t *testing.T) {
+	const inn = "7707083893"
+	fresh := storage.Counterparty{INN: inn, KPP: "773601001", Name: "ПАО Сбербанк", Address: "Москва", Source: "egrul"}
+
+	tests := []struct {
+		name      string
+		cached    *storage.Counterparty
+		manual    map[string]string // поле -> ручное значение поверх cached
+		age       time.Duration
+		lookupErr error
+		force     bool
+		wantCalls int
+		wantName  string
+		wantErr   bool
+	}{
+		{name: "no cache", wantCalls: 1, wantName: "ПАО Реестр"},
+		{name: "fresh cache", cached: &fresh, age: time.Hour, wantCalls: 0, wantName: "ПАО Сбербанк"},
+		{name: "stale cache", cached: &fresh, age: 48 * time.Hour, wantCalls: 1, wantName: "ПАО Реестр"},
+		{name: "stale cache, registry down", cached: &fresh, age: 48 * time.Hour, lookupErr: errors.New("timeout"), wantCalls: 1, wantName: "ПАО Сбербанк"},
+		{name: "manual edit never expires", cached: &fresh, age: 48 * time.Hour, manual: map[string]string{storage.CounterpartyName: "ПАО Сбер"}, wantCalls: 0, wantName: "ПАО Сбер"},
+		{name: "force ignores manual", cached: &fresh, manual: map[string]string{storage.CounterpartyName: "ПАО Сбер"}, force: true, wantCalls: 1, wantName: "ПАО Реестр"},
+		{name: "manual row without name is refreshed", cached: &storage.Counterparty{INN: inn, Source: counterpartyManualSource}, manual: map[string]string{storage.CounterpartyKPP: "773601001"}, wantCalls: 1, wantName: "ПАО Реестр"},
+		{name: "no cache, registry down", lookupErr: companylookup.ErrNotFound, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := storage.MustOpen(filepath.Join(t.TempDir(), "test.db"))
+			defer db.Close()
+
+			if tt.cached != nil {
+				c := *tt.cached
+				c.FetchedAt = time.Now().Add(-tt.age)
+				if err := storage.SaveCounterparty(db, &c); err != nil {
+					t.Fatal(err)
+				}
+				for field, v := range tt.manual {
+					if ok, err := storage.SetCounterpartyField(db, inn, field, v, 1); err != nil || !ok {
+						t.Fatalf("SetCounterpartyField = %v, %v", ok, err)
+					}
+				}
+			}
+
+			l := &countingLookup{co: companylookup.Company{KPP: "773601001", Name: "ПАО Реестр", Source: companylookup.ProviderEGRUL}, err: tt.lookupErr}
+			b := &Bot{db: db, cfg: &config.Config{CounterpartyTTL: 24 * time.Hour}, lookup: l}
+
+			co, err := b.lookupCounterparty(context.Background(), inn, tt.force)
+			if l.calls != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", l.calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %+v", co)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lookupCounterparty: %v", err)
+			}
+			if co.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", co.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCounterpartyCardSource(t *testing.T) {
+	db := storage.MustOpen(filepath.Join(t.TempDir(), "bot.db"))
+	defer db.Close()
+	api, sent := recordingTelegram(t)
+	b := &Bot{bot: api, out: sender.New(api), db: db, cfg: &config.Config{CounterpartyTTL: time.Hour}}
+
+	const inn = "7707083893"
+	if err := storage.SaveCounterparty(db, &storage.Counterparty{
+		INN: inn, Name: "ПАО СБЕРБАНК", Source: companylookup.ProviderRusprofile, FetchedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	b.sendCounterpartyCard(1, inn)
+	if _, err := storage.SetCounterpartyField(db, inn, storage.CounterpartyName, "ПАО Сбербанк России", 5); err != nil {
+		t.Fatal(err)
+	}
+	b.sendCounterpartyCard(1, inn)
+
+	reqs := sent()
+	if len(reqs) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(reqs))
+	}
+	if !strings.Contains(reqs[0].Text, "Источник: Rusprofile") {
+		t.Errorf("card before edit:\n%s", reqs[0].Text)
+	}
+	if !strings.Contains(reqs[1].Text, "Источник: вручную") || !strings.Contains(reqs[1].Text, "Исправлено вручную") {
+		t.Errorf("card after manual edit:\n%s", reqs[1].Text)
+	}
+}
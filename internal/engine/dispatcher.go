@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dispatcher раздаёт апдейты пулу обработчиков. Апдейты одного чата идут строго по очереди
+// (FSM мастера заявки и навигатора рассчитаны на порядок), разные чаты — параллельно,
+// но одновременно работает не больше workers обработчиков.
+type dispatcher struct {
+	handle func(context.Context, tgbotapi.Update) // b.safeHandleUpdate; в тестах подменяется
+	ctx    context.Context
+	sem    chan struct{}
+
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update // ключ очереди -> ещё не обработанные апдейты
+
+	wg sync.WaitGroup
+}
+
+func newDispatcher(ctx context.Context, b *Bot, workers int) *dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &dispatcher{
+		handle: b.safeHandleUpdate,
+		ctx:    ctx,
+		sem:    make(chan struct{}, workers),
+		queues: map[int64][]tgbotapi.Update{},
+	}
+}
+
+// Dispatch не блокируется: апдейт встаёт в очередь своего чата.
+func (d *dispatcher) Dispatch(upd tgbotapi.Update) {
+	key := updateQueueKey(upd)
+
+	d.mu.Lock()
+	q, busy := d.queues[key]
+	d.queues[key] = append(q, upd)
+	d.mu.Unlock()
+
+	// у этого чата уже есть горутина-разборщик — она заберёт и этот апдейт
+	if busy {
+		return
+	}
+
+	d.wg.Add(1)
+	go d.drain(key)
+}
+
+func (d *dispatcher) drain(key int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		q := d.queues[key]
+		if len(q) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		upd := q[0]
+		d.queues[key] = q[1:]
+		d.mu.Unlock()
+
+		d.sem <- struct{}{}
+		d.handle(d.ctx, upd)
+		<-d.sem
+	}
+}
+
+// Wait дожидается, пока разберутся все поставленные апдейты.
+func (d *dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// updateQueueKey: очередь на чат (личка пользователя, навигатор, группа подтверждения).
+func updateQueueKey(upd tgbotapi.Update) int64 {
+	if upd.Message != nil && upd.Message.Chat != nil {
+		return upd.Message.Chat.ID
+	}
+	if cq := upd.CallbackQuery; cq != nil {
+		if cq.Message != nil && cq.Message.Chat != nil {
+			return cq.Message.Chat.ID
+		}
+		if cq.From != nil {
+			return cq.From.ID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(id int, chat int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chat}}}
+}
+
+func TestDispatcherKeepsPerChatOrder(t *testing.T) {
+	const (
+		chats   = 5
+		perChat = 50
+		workers = 3
+	)
+
+	var (
+		mu       sync.Mutex
+		seen     = map[int64][]int{}
+		inChat   = map[int64]bool{}
+		running  int
+		peak     int
+		overlaps int
+	)
+	d := newDispatcher(context.Background(), nil, workers)
+	d.handle = func(_ context.Context, upd tgbotapi.Update) {
+		chat := upd.Message.Chat.ID
+		mu.Lock()
+		if inChat[chat] {
+			overlaps++
+		}
+		inChat[chat] = true
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(100 * time.Microsecond)
+
+		mu.Lock()
+		seen[chat] = append(seen[chat], upd.UpdateID)
+		inChat[chat] = false
+		running--
+		mu.Unlock()
+	}
+
+	// апдейты чатов перемешаны, как приходят из long polling
+	for i := range perChat {
+		for c := range int64(chats) {
+			d.Dispatch(chatUpdate(i, c+1))
+		}
+	}
+	d.Wait()
+
+	if overlaps != 0 {
+		t.Errorf("обработчики одного чата пересеклись %d раз", overlaps)
+	}
+	if peak > workers {
+		t.Errorf("одновременно работало %d обработчиков, лимит %d", peak, workers)
+	}
+	for c := range int64(chats) {
+		got := seen[c+1]
+		if len(got) != perChat {
+			t.Fatalf("чат %d: обработано %d апдейтов, ждали %d", c+1, len(got), perChat)
+		}
+		for i, id := range got {
+			if id != i {
+				t.Fatalf("чат %d: порядок нарушен: %v", c+1, got)
+			}
+		}
+	}
+	if len(d.queues) != 0 {
+		t.Errorf("после Wait остались очереди: %v", d.queues)
+	}
+}
+
+func TestDispatcherRunsChatsInParallel(t *testing.T) {
+	d := newDispatcher(context.Background(), nil, 2)
+	release := make(chan struct{})
+	started := make(chan int64, 2)
+	d.handle = func(_ context.Context, upd tgbotapi.Update) {
+		started <- upd.Message.Chat.ID
+		<-release
+	}
+
+	// первый чат висит, второй всё равно должен начаться
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 2))
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(2 * time.Second):
+			t.Fatal("второй чат ждёт первый")
+		}
+	}
+	close(release)
+	d.Wait()
+}
+
+func TestUpdateQueueKey(t *testing.T) {
+	tests := []struct {
+		name string
+		upd  tgbotapi.Update
+		want int64
+	}{
+		{"сообщение", chatUpdate(1, 42), 42},
+		{"кнопка в чате", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			From:    &tgbotapi.User{ID: 7},
+			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: -100}},
+		}}, -100},
+		{"inline-кнопка без сообщения", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: &tgbotapi.User{ID: 7}}}, 7},
+		{"прочее", tgbotapi.Update{}, 0},
+	}
+	for _, tt := range tests {
+		if got := updateQueueKey(tt.upd); got != tt.want {
+			t.Errorf("%s: updateQueueKey = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/calendar"
+	"TGBOT2/internal/companylookup"
+	"TGBOT2/internal/config"
+	"TGBOT2/internal/sender"
+	"TGBOT2/internal/storage"
+)
+
+// Bot — экземпляр движка для одного телеграм-бота.
+// Всё изменяемое состояние (сессии навигатора, черновики заявок) живёт здесь,
+// а не в глобальных переменных, чтобы несколько ботов не мешали друг другу.
+type Bot struct {
+	bot     *tgbotapi.BotAPI
+	out     *sender.Sender // все исходящие сообщения — через него (лимиты, 429, повторы)
+	db      *sql.DB
+	cfg     *config.Config
+	profile Profile
+	cal     *calendar.Calendar          // общий для всех ботов процесса
+	lookup  companylookup.CompanyLookup // контрагент по ИНН (мастер заявки)
+
+	navMu       sync.Mutex
+	navSessions map[navSessionKey]*navSession
+
+	appMu     sync.Mutex
+	appByUser map[int64]*userAppState // telegram_id -> state
+
+	// ✅ метим сообщения, которые ушли в поддержку (для reply в ответе навигатора)
+	supportMu        sync.RWMutex
+	supportQuestions map[string]bool // key = "chatID:msgID"
+
+	// долгие задачи обработчиков (рассылка «Отправить сейчас»); Run/ServeWebhooks их дожидаются
+	bg sync.WaitGroup
+}
+
+// New: lookup нужен только профилю с заявками (см. NewCompanyLookup), остальным — nil.
+func New(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, p Profile, cal *calendar.Calendar, lookup companylookup.CompanyLookup) *Bot {
+	b := &Bot{
+		bot:              bot,
+		out:              sender.New(bot),
+		db:               db,
+		cfg:              cfg,
+		profile:          p,
+		cal:              cal,
+		navSessions:      map[navSessionKey]*navSession{},
+		appByUser:        map[int64]*userAppState{},
+		supportQuestions: map[string]bool{},
+	}
+	b.out.OnForbidden = b.markUnreachable
+
+	if p.Applications {
+		b.lookup = lookup
+		b.logf("company lookup: %s", lookup.Name())
+	}
+	return b
+}
+
+// NewCompanyLookup собирает поиск контрагентов по COMPANY_LOOKUP.
+// Ошибка настройки (опечатка в имени, dadata без ключа, fake без файла) — повод не стартовать,
+// а не молча перейти на другой источник.
+func NewCompanyLookup(cfg *config.Config) (companylookup.CompanyLookup, error) {
+	return companylookup.New(cfg.CompanyLookup, companylookup.Options{
+		DaDataAPIKey: cfg.DaDataAPIKey,
+		FakePath:     cfg.CompanyLookupFakePath,
+		FixturesDir:  cfg.CompanyLookupFixtures,
+	})
+}
+
+// markUnreachable: пользователь заблокировал бота (403) — больше не пишем ему из рассылок
+// и напоминаний, пока он сам не напишет боту (см. storage.UpsertUser).
+func (b *Bot) markUnreachable(chatID int64, err error) {
+	marked, dbErr := storage.MarkUserUnreachable(b.db, b.profile.Name, chatID)
+	if dbErr != nil {
+		b.logf("MarkUserUnreachable chat=%d error: %v", chatID, dbErr)
+		return
+	}
+	if marked {
+		b.logf("chat %d marked unreachable: %v", chatID, err)
+	}
+}
+
+func (b *Bot) Profile() Profile {
+	return b.profile
+}
+
+// HandleUpdate — единая точка входа для апдейта телеграма.
+func (b *Bot) HandleUpdate(ctx context.Context, upd tgbotapi.Update) {
+	// ✅ CALLBACKS (inline кнопки)
+	if upd.CallbackQuery != nil {
+		// навигаторская рассылка
+		b.HandleBroadcastCallback(ctx, upd.CallbackQuery)
+
+		// подтверждение/правка заявки в группе, проверка заявки в личке
+		if b.profile.Applications {
+			b.HandleApprovalCallback(ctx, upd.CallbackQuery)
+			b.HandleApplicationCallback(ctx, upd.CallbackQuery)
+		}
+		return
+	}
+
+	if upd.Message == nil {
+		return
+	}
+	m := upd.Message
+
+	// /chatid чтобы узнавать id чатов/групп
+	if m.IsCommand() && m.Command() == "chatid" {
+		chatID := m.Chat.ID
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("chat_id = %d", chatID)))
+		return
+	}
+
+	if m.Chat == nil {
+		return
+	}
+
+	// сообщения в группе подтверждения (ждём reply после "Правка").
+	// Важно: это должно отрабатывать ДО навигатора/лички.
+	if b.profile.Applications && b.profile.ApprovalChatID != 0 && m.Chat.ID == b.profile.ApprovalChatID {
+		b.HandleApprovalGroupMessage(m)
+		return
+	}
+
+	// бухгалтерские группы или чат навигатора
+	if (b.profile.NavigatorChatID != 0 && m.Chat.ID == b.profile.NavigatorChatID) || b.profile.isAccountingChat(m.Chat.ID) {
+		b.HandleNavigatorBroadcast(ctx, m) // /start, /broadcast, кнопки панели
+		b.HandleSupportReply(ctx, m)       // ответы сотрудников пользователям (reply)
+		return
+	}
+
+	// пользователи (только личка)
+	if m.Chat.IsPrivate() {
+		b.HandleUserMessage(ctx, m)
+	}
+}
+
+// send — для интерактивных ответов: одно-два сообщения, отменять их на остановке незачем.
+// Массовые отправки (рассылки, напоминания) идут через b.out.Send с контекстом.
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.out.Send(context.Background(), c)
+}
+
+// goBackground — задача, которая не должна держать очередь апдейтов чата.
+// Вызывать только из обработчиков: на остановке её дожидаются после очереди апдейтов.
+func (b *Bot) goBackground(fn func()) {
+	b.bg.Add(1)
+	go func() {
+		defer b.bg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				b.logf("panic in background task: %v\n%s", r, debug.Stack())
+			}
+		}()
+		fn()
+	}()
+}
+
+func (b *Bot) logf(format string, args ...any) {
+	log.Printf(b.profile.Name+" "+format, args...)
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/config"
+	"TGBOT2/internal/storage"
+)
+
+// UserRef: "@username" или "id:123"
+func UserRef(u *tgbotapi.User) string {
+	if u == nil {
+		return "id:unknown"
+	}
+	if strings.TrimSpace(u.UserName) != "" {
+		return "@" + strings.TrimSpace(u.UserName)
+	}
+	return fmt.Sprintf("id:%d", u.ID)
+}
+
+// ResponderAlias: алиас из ENV (RESPONDER_ALIASES), иначе @username, иначе id:123
+// ВАЖНО: возвращаем БЕЗ двоеточия. Двоеточие добавляем в тексте ответа.
+func ResponderAlias(cfg *config.Config, from *tgbotapi.User) string {
+	if from == nil {
+		return "unknown"
+	}
+
+	if a, ok := cfg.ResponderAliases[int64(from.ID)]; ok && strings.TrimSpace(a) != "" {
+		return strings.TrimSpace(a)
+	}
+	if strings.TrimSpace(from.UserName) != "" {
+		return "@" + strings.TrimSpace(from.UserName)
+	}
+	return fmt.Sprintf("id:%d", from.ID)
+}
+
+func parseMoney(s string) (float64, error) {
+	clean := strings.TrimSpace(s)
+	clean = strings.ReplaceAll(clean, " ", "")
+	clean = strings.ReplaceAll(clean, "\u00a0", "")
+	clean = strings.ReplaceAll(clean, ",", ".")
+	if clean == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+	val, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad amount %q", s)
+	}
+	return val, nil
+}
+
+func StartText() string {
+	return `Привет! 👋
+Я успешно связал Вас с командой поддержки.
+
+Как только сотрудники увидят Ваше сообщение,
+они обязательно Вам ответят.
+
+Вы можете написать свой вопрос прямо сейчас.`
+}
+
+func nz(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "—"
+	}
+	return strings.TrimSpace(s)
+}
+
+func moscowLocation() *time.Location {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		loc = time.FixedZone("MSK", 3*60*60)
+	}
+	return loc
+}
+
+func mkUser(m *tgbotapi.Message) *storage.User {
+	u := &storage.User{
+		TelegramID: int64(m.From.ID),
+		ChatID:     m.Chat.ID,
+	}
+	username := strings.TrimSpace(m.From.UserName)
+	first := strings.TrimSpace(m.From.FirstName)
+	last := strings.TrimSpace(m.From.LastName)
+	if username != "" {
+		u.Username = &username
+	}
+	if first != "" {
+		u.FirstName = &first
+	}
+	if last != "" {
+		u.LastName = &last
+	}
+	return u
+}
+
+func (b *Bot) sendHeaderAndMap(dstChatID int64, text string, userChatID int64, userMessageID int) {
+	msg := tgbotapi.NewMessage(dstChatID, text)
+	sent, err := b.send(msg)
+	if err != nil {
+		b.logf("send header error dst=%d: %v", dstChatID, err)
+		return
+	}
+	_ = storage.AddMap(b.db, dstChatID, sent.MessageID, userChatID, userMessageID)
+}
+
+func (b *Bot) forwardAndMap(dstChatID int64, srcChatID int64, srcMsgID int, userChatID int64, userMessageID int) {
+	fwd := tgbotapi.NewForward(dstChatID, srcChatID, srcMsgID)
+	sent, err := b.send(fwd)
+	if err != nil {
+		b.logf("forward error dst=%d: %v", dstChatID, err)
+		return
+	}
+	_ = storage.AddMap(b.db, dstChatID, sent.MessageID, userChatID, userMessageID)
+}
+
+func (b *Bot) markSupportQuestion(chatID int64, msgID int) {
+	b.supportMu.Lock()
+	defer b.supportMu.Unlock()
+	b.supportQuestions[fmt.Sprintf("%d:%d", chatID, msgID)] = true
+}
+
+func (b *Bot) isSupportQuestion(chatID int64, msgID int) bool {
+	b.supportMu.RLock()
+	defer b.supportMu.RUnlock()
+	return b.supportQuestions[fmt.Sprintf("%d:%d", chatID, msgID)]
+}
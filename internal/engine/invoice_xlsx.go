@@ -1,4 +1,4 @@
-package tg3
+package engine
 
 import (
 	"fmt"
@@ -185,7 +185,7 @@ func moneyToWordsRU(amount float64) string {
 // ----- Твой основной метод заполнения -----
 
 // applicationDraft должен быть ТВОЙ (из твоего проекта). Здесь используются поля:
-// INN, LegalName, RusKPP, RusName, RusAddress, Contract.
+// INN, LegalName, RusKPP/KPP, RusName, RusAddress, Contract.
 func FillInvoiceTemplateXLSX(
 	templatePath string,
 	outDir string,
@@ -216,15 +216,17 @@ func FillInvoiceTemplateXLSX(
 	}
 
 	// A9: "Счёт на оплату № N от 20 января 2026 г."
-	_ = f.SetCellValue(sheet, "A9", fmt.Sprintf("Счёт на оплату № %d от %s", invoiceNo, ruDateWords(invoiceDate)))
-
-	// E13: "название юр. лица, ИНН, КПП, Адрес"
-	name := strings.TrimSpace(draft.RusName)
-	if name == "" {
-		name = strings.TrimSpace(draft.LegalName)
+	if invoiceNo == 0 {
+		// предпросмотр до отправки: номер присваивается только при отправке на подтверждение
+		_ = f.SetCellValue(sheet, "A9", fmt.Sprintf("Счёт на оплату № ____ от %s (предпросмотр)", ruDateWords(invoiceDate)))
+	} else {
+		_ = f.SetCellValue(sheet, "A9", fmt.Sprintf("Счёт на оплату № %d от %s", invoiceNo, ruDateWords(invoiceDate)))
 	}
+
+	// E13: "название юр. лица, ИНН, КПП, Адрес" (у ИП — без КПП)
+	name := buyerName(&draft)
 	inn := strings.TrimSpace(draft.INN)
-	kpp := strings.TrimSpace(draft.RusKPP)
+	kpp := draftKPP(&draft)
 	addr := strings.TrimSpace(draft.RusAddress)
 
 	var e13parts []string
@@ -288,8 +290,7 @@ func FillInvoiceTemplateXLSX(
 
 	total = math.Round(total*100) / 100
 
-	// ✅ НДС 22% по твоей формуле: total/122*22
-	vat := math.Round((total/122.0*22.0)*100) / 100
+	vat := invoiceVAT(total)
 
 	// Итоги по твоему шаблону: P20/P21/P22 + A23/A24/A26
 	offset := len(items) - 1
@@ -335,6 +336,11 @@ func FillInvoiceTemplateXLSX(
 	return outPath, nil
 }
 
+// invoiceVAT — НДС 22%, включённый в сумму: total/122*22
+func invoiceVAT(total float64) float64 {
+	return math.Round((total/122.0*22.0)*100) / 100
+}
+
 func capitalizeFirst(s string) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"TGBOT2/internal/config"
+)
+
+// libreOfficeSem — один soffice за раз: параллельные запуски делят профиль и мешают друг другу.
+// Канал, а не мьютекс, чтобы ожидающие в очереди выходили по отмене ctx.
+var libreOfficeSem = make(chan struct{}, 1)
+
+func ConvertXLSXToPDFLibreOffice(ctx context.Context, cfg *config.Config, xlsxPath, outDir string) (string, error) {
+	if strings.TrimSpace(xlsxPath) == "" {
+		return "", fmt.Errorf("xlsxPath is empty")
+	}
+	if strings.TrimSpace(outDir) == "" {
+		outDir = os.TempDir()
+	}
+
+	select {
+	case libreOfficeSem <- struct{}{}:
+	case <-ctx.Done():
+		return "", fmt.Errorf("soffice cancelled: %w", ctx.Err())
+	}
+	defer func() { <-libreOfficeSem }()
+
+	inAbs, err := filepath.Abs(xlsxPath)
+	if err != nil {
+		return "", fmt.Errorf("abs input: %w", err)
+	}
+
+	base := filepath.Base(inAbs)
+	pdfName := strings.TrimSuffix(base, filepath.Ext(base)) + ".pdf"
+	outPDF := filepath.Join(outDir, pdfName)
+
+	_ = os.Remove(outPDF)
+
+	soffice := "soffice"
+	if cfg != nil && strings.TrimSpace(cfg.SofficePath) != "" {
+		soffice = cfg.SofficePath
+	}
+
+	// ✅ при отмене ctx (остановка бота) или по таймауту soffice убивается, а не остаётся висеть
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(
+		ctx,
+		soffice,
+		"--headless",
+		"--nologo",
+		"--nofirststartwizard",
+		"--convert-to", "pdf",
+		"--outdir", outDir,
+		inAbs,
+	)
+	// soffice — лаунчер, конвертирует дочерний soffice.bin: убиваем всю группу процессов,
+	// иначе soffice.bin переживает таймаут, держит блокировку профиля и следующие конвертации молча не работают
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// не ждём вечно stdout/stderr, если дочерние процессы soffice их держат
+	cmd.WaitDelay = 5 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("soffice timeout; stderr=%s; stdout=%s", stderr.String(), stdout.String())
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("soffice cancelled: %w", ctx.Err())
+		}
+		return "", fmt.Errorf("soffice failed: %w; stderr=%s; stdout=%s", err, stderr.String(), stdout.String())
+	}
+
+	if _, err := os.Stat(outPDF); err != nil {
+		return "", fmt.Errorf("pdf not created: %w; stderr=%s; stdout=%s", err, stderr.String(), stdout.String())
+	}
+
+	return outPDF, nil
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"TGBOT2/internal/config"
+)
+
+// fakeSoffice — лаунчер, который, как настоящий soffice, запускает дочерний процесс и ждёт его.
+func fakeSoffice(t *testing.T, pidFile string) *config.Config {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "soffice")
+	body := "#!/bin/sh\nsleep 30 &\necho $! > " + pidFile + "\nwait\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{SofficePath: script}
+}
+
+func TestConvertKillsSofficeChildren(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	cfg := fakeSoffice(t, pidFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := ConvertXLSXToPDFLibreOffice(ctx, cfg, filepath.Join(dir, "in.xlsx"), dir)
+		done <- err
+	}()
+
+	var pid int
+	for deadline := time.Now().Add(5 * time.Second); pid == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("дочерний процесс не запустился")
+		}
+		b, _ := os.ReadFile(pidFile)
+		pid, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "cancelled") {
+			t.Fatalf("err = %v, want cancelled", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("конвертация не вернулась после отмены")
+	}
+
+	// процесс убит и уже подобран init'ом — ждём, пока исчезнет
+	for deadline := time.Now().Add(5 * time.Second); syscall.Kill(pid, 0) == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("дочерний процесс %d пережил отмену", pid)
+		}
+	}
+}
+
+func TestConvertQueueRespectsContext(t *testing.T) {
+	libreOfficeSem <- struct{}{}
+	defer func() { <-libreOfficeSem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	dir := t.TempDir()
+	_, err := ConvertXLSXToPDFLibreOffice(ctx, &config.Config{SofficePath: "/nonexistent"}, filepath.Join(dir, "in.xlsx"), dir)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("err = %v, want cancelled while waiting for soffice", err)
+	}
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// StartScheduledBroadcasts: отложенные и регулярные рассылки хранятся в БД, поэтому переживают рестарт.
+// Напоминание о конце приёма заявок — тоже регулярная рассылка (заводится при первом запуске).
+func (b *Bot) StartScheduledBroadcasts(ctx context.Context) {
+	b.RecoverScheduledBroadcasts()
+	b.ensureBuiltinRecurring()
+
+	// начатая рассылка доотправляется в пределах ShutdownTimeout
+	hctx, cancel := b.handlerContext(ctx)
+	defer cancel()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.RunDueScheduledBroadcasts(hctx)
+			b.RunDueRecurringBroadcasts(hctx)
+		}
+	}
+}
@@ -0,0 +1,926 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+type BroadcastStage string
+
+const (
+	bStageIdle               BroadcastStage = "idle"
+	bStageAwaitTemplate      BroadcastStage = "await_template"
+	bStageAwaitSchedule      BroadcastStage = "await_schedule"
+	bStageAwaitAudienceDays  BroadcastStage = "await_audience_days"
+	bStageAwaitAudienceList  BroadcastStage = "await_audience_list"
+	bStageAwaitRecurringSpec BroadcastStage = "await_recurring_spec"
+	bStageAwaitBlock         BroadcastStage = "await_block"
+	bStageAwaitUnblock       BroadcastStage = "await_unblock"
+	bStageAwaitDirectTarget  BroadcastStage = "await_direct_target"
+	bStageAwaitDirectMessage BroadcastStage = "await_direct_message"
+
+	bStageAwaitCounterpartyValue BroadcastStage = "await_counterparty_value"
+)
+
+type BroadcastPayload struct {
+	Text           string
+	DocumentFileID string
+	PhotoFileID    string
+
+	// ✅ шаблон из чата навигатора рассылаем копией (copyMessage, для альбома copyMessages):
+	// так сохраняются форматирование, ссылки и любые вложения. SourceChatID == 0 — старая
+	// запланированная рассылка, отправляется из полей выше.
+	SourceChatID int64
+	MessageIDs   []int
+	Kind         string // см. templateKind
+
+	MediaGroupID string // пока собираем части альбома
+}
+
+type navBroadcastState struct {
+	Stage    BroadcastStage
+	Payload  *BroadcastPayload
+	Audience *storage.Audience // nil — ещё не выбрана
+
+	albumTimer *time.Timer // дожидаемся остальных частей альбома
+
+	DirectUserChatID int64
+	DirectUserRef    string
+
+	// ручная правка контрагента (/counterparty)
+	CounterpartyINN   string
+	CounterpartyField string
+}
+
+// =====================
+// Public handlers
+// =====================
+
+func (b *Bot) HandleNavigatorBroadcast(ctx context.Context, m *tgbotapi.Message) {
+	if m == nil || m.Chat == nil {
+		return
+	}
+	if b.profile.NavigatorChatID == 0 || m.Chat.ID != b.profile.NavigatorChatID {
+		return
+	}
+	if m.From == nil || ctx.Err() != nil {
+		return
+	}
+
+	// ✅ у каждого сотрудника в навигаторском чате своя сессия
+	s, unlock := b.lockNavSession(m.Chat.ID, int64(m.From.ID))
+	defer unlock()
+
+	// /start
+	if m.IsCommand() && m.Command() == "start" {
+		b.sendNavigatorWelcome(m.Chat.ID)
+		return
+	}
+
+	// /broadcast
+	if m.IsCommand() && m.Command() == "broadcast" {
+		b.startBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	// /report [N]
+	if m.IsCommand() && m.Command() == "report" {
+		b.sendBroadcastReport(m.Chat.ID, m.CommandArguments())
+		return
+	}
+
+	// /calendar [reload]
+	if m.IsCommand() && m.Command() == "calendar" {
+		b.handleCalendarCommand(m)
+		return
+	}
+
+	// /counterparty [ИНН] — кэш контрагентов мастера заявки
+	if m.IsCommand() && m.Command() == "counterparty" && b.profile.Applications {
+		b.handleCounterpartyCommand(m.Chat.ID, m.CommandArguments())
+		return
+	}
+
+	// ====== FSM: block/unblock ======
+	if s.Stage == bStageAwaitBlock {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		b.handleBlockInput(s, m)
+		return
+	}
+	if s.Stage == bStageAwaitUnblock {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		b.handleUnblockInput(s, m)
+		return
+	}
+
+	// ====== FSM: direct message ======
+	if s.Stage == bStageAwaitDirectTarget {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		b.handleDirectTargetInput(s, m)
+		return
+	}
+	if s.Stage == bStageAwaitDirectMessage {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		b.handleDirectMessageSend(s, m)
+		return
+	}
+
+	// ====== FSM: counterparty correction ======
+	if s.Stage == bStageAwaitCounterpartyValue {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		b.handleCounterpartyValueInput(s, m)
+		return
+	}
+
+	// ====== FSM: broadcast audience ======
+	if s.Stage == bStageAwaitAudienceDays && s.Payload != nil {
+		b.handleAudienceDaysInput(s, m)
+		return
+	}
+	if s.Stage == bStageAwaitAudienceList && s.Payload != nil {
+		b.handleAudienceListInput(s, m)
+		return
+	}
+
+	// ====== FSM: recurring schedule ======
+	if s.Stage == bStageAwaitRecurringSpec && s.Payload != nil && s.Audience != nil {
+		b.handleRecurringSpecInput(s, m)
+		return
+	}
+
+	// ====== FSM: broadcast schedule time ======
+	if s.Stage == bStageAwaitSchedule && s.Payload != nil && s.Audience != nil {
+		b.handleScheduleTimeInput(s, m)
+		return
+	}
+
+	// ====== FSM: broadcast template ======
+	if s.Stage == bStageAwaitTemplate {
+		b.captureBroadcastTemplate(s, m)
+		return
+	}
+
+	// ====== Buttons (when idle) ======
+	txt := strings.TrimSpace(m.Text)
+
+	if txt == "📨 Рассылка" {
+		b.startBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	if txt == "🗓 Запланированные" {
+		b.sendScheduledList(m.Chat.ID)
+		return
+	}
+
+	if txt == "🔁 Регулярные" {
+		b.sendRecurringList(m.Chat.ID)
+		return
+	}
+
+	if txt == "📊 Отчёты" {
+		b.sendRecentBroadcasts(m.Chat.ID)
+		return
+	}
+
+	if txt == "🚫 Блокировка" && b.profile.BlockUnblock {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		s.Stage = bStageAwaitBlock
+		s.Payload = nil
+		s.DirectUserChatID = 0
+		s.DirectUserRef = ""
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Введите telegram id (число) или @username для блокировки.\nОтмена: «❌ Отмена».")
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	if txt == "✅ Разблокировать" && b.profile.BlockUnblock {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+		s.Stage = bStageAwaitUnblock
+		s.Payload = nil
+		s.DirectUserChatID = 0
+		s.DirectUserRef = ""
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Введите telegram id (число) или @username для разблокировки.\nОтмена: «❌ Отмена».")
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	if txt == "✉️ Написать" {
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			return
+		}
+
+		s.Stage = bStageAwaitDirectTarget
+		s.Payload = nil
+		s.DirectUserChatID = 0
+		s.DirectUserRef = ""
+
+		msg := tgbotapi.NewMessage(m.Chat.ID,
+			"Введите telegram id (число) или @username пользователя (allowed=1 и не в бане).\nОтмена: «❌ Отмена».")
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+}
+
+func (b *Bot) HandleBroadcastCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	if cq == nil || cq.Message == nil || cq.Message.Chat == nil {
+		return
+	}
+	if b.profile.NavigatorChatID == 0 || cq.Message.Chat.ID != b.profile.NavigatorChatID {
+		return
+	}
+
+	_, _ = b.bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+
+	if strings.HasPrefix(cq.Data, "sched_cancel:") {
+		b.handleScheduledCancelCallback(cq)
+		return
+	}
+	if strings.HasPrefix(cq.Data, "rec_") {
+		b.handleRecurringCallback(cq)
+		return
+	}
+	if strings.HasPrefix(cq.Data, "bc_report:") || strings.HasPrefix(cq.Data, "bc_failed_csv:") {
+		b.handleBroadcastReportCallback(cq)
+		return
+	}
+	if strings.HasPrefix(cq.Data, "cp_refresh:") {
+		b.handleCounterpartyRefresh(ctx, cq)
+		return
+	}
+
+	if cq.From == nil {
+		return
+	}
+
+	// кнопки рассылки относятся к сессии того, кто её готовил
+	s, unlock := b.lockNavSession(cq.Message.Chat.ID, int64(cq.From.ID))
+	defer unlock()
+
+	if strings.HasPrefix(cq.Data, "bc_aud:") {
+		b.handleAudienceCallback(s, cq.Message.Chat.ID, cq.Data)
+		return
+	}
+	if strings.HasPrefix(cq.Data, "cp_edit:") {
+		b.handleCounterpartyEdit(ctx, s, cq)
+		return
+	}
+
+	switch cq.Data {
+	case "broadcast_send_now":
+		if s.Payload == nil || s.Audience == nil {
+			return
+		}
+		payload, audience := s.Payload, *s.Audience
+		s.Stage = bStageIdle
+		s.Payload = nil
+		s.Audience = nil
+
+		chatID, createdBy := cq.Message.Chat.ID, int64(cq.From.ID)
+		msg := tgbotapi.NewMessage(chatID, "Рассылка запущена, отчёт пришлю, когда закончится.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+
+		// ✅ с лимитами отправки это минуты: не держим очередь чата навигатора (ответы поддержки и т.п.)
+		b.goBackground(func() {
+			res := b.broadcastToAudience(ctx, payload, audience, broadcastSource{CreatedBy: createdBy})
+			_, _ = b.send(tgbotapi.NewMessage(chatID, broadcastDoneText("Рассылка", res)))
+		})
+
+	case "broadcast_schedule":
+		if s.Payload == nil || s.Audience == nil {
+			return
+		}
+		s.Stage = bStageAwaitSchedule
+
+		text := "Введите дату и время отправки в формате `DD.MM.YYYY HH:MM` (по Москве).\nНапример: `05.12.2025 10:30`"
+		msg := tgbotapi.NewMessage(cq.Message.Chat.ID, text)
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+
+	case "broadcast_recurring":
+		if s.Payload == nil || s.Audience == nil {
+			return
+		}
+		s.Stage = bStageAwaitRecurringSpec
+
+		msg := tgbotapi.NewMessage(cq.Message.Chat.ID, recurringSpecHelp)
+		msg.ReplyMarkup = directMsgKeyboard()
+		_, _ = b.send(msg)
+
+	case "broadcast_cancel":
+		s.Stage = bStageIdle
+		s.Payload = nil
+		s.Audience = nil
+
+		msg := tgbotapi.NewMessage(cq.Message.Chat.ID, "Рассылка отменена.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+	}
+}
+
+// =====================
+// Keyboards
+// =====================
+
+func (b *Bot) navigatorMainKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	row := []tgbotapi.KeyboardButton{tgbotapi.NewKeyboardButton("📨 Рассылка")}
+	if b.profile.BlockUnblock {
+		row = append(row,
+			tgbotapi.NewKeyboardButton("🚫 Блокировка"),
+			tgbotapi.NewKeyboardButton("✅ Разблокировать"),
+		)
+	}
+	row = append(row, tgbotapi.NewKeyboardButton("✉️ Написать"))
+
+	kb := tgbotapi.NewReplyKeyboard(
+		row,
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("🗓 Запланированные"),
+			tgbotapi.NewKeyboardButton("🔁 Регулярные"),
+			tgbotapi.NewKeyboardButton("📊 Отчёты"),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func directMsgKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("❌ Отмена"),
+		),
+	)
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+func (b *Bot) sendNavigatorWelcome(chatID int64) {
+	text := fmt.Sprintf("Панель навигатора (%s):\n\n", b.profile.Title) +
+		"📨 Рассылка — отправка пользователям (все, по компании, активные, списком)\n"
+	if b.profile.BlockUnblock {
+		text += "🚫 Блокировка — бот полностью игнорирует пользователя\n" +
+			"✅ Разблокировать — снять блокировку\n"
+	}
+	text += "✉️ Написать — написать конкретному пользователю\n" +
+		"🗓 Запланированные — список отложенных рассылок и их отмена\n" +
+		"🔁 Регулярные — рассылки по расписанию (пауза, удаление)\n" +
+		"📊 Отчёты — кому рассылка дошла, а кому нет (/report N)\n" +
+		"📅 /calendar — производственный календарь, /calendar reload — перечитать файл"
+	if b.profile.Applications {
+		text += "\n🏢 /counterparty <ИНН> — данные контрагента из кэша: обновить из реестра или исправить"
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+// =====================
+// 🚫 Block / ✅ Unblock
+// =====================
+
+func (b *Bot) handleBlockInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "" {
+		return
+	}
+
+	if txt == "❌ Отмена" {
+		s.Stage = bStageIdle
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Отменено.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	var telegramID int64
+	var ok bool
+	var err error
+
+	if strings.HasPrefix(txt, "@") {
+		telegramID, ok, err = storage.GetTelegramIDByUsername(b.db, txt)
+		if err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Ошибка поиска по @username."))
+			return
+		}
+		if !ok {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь не найден в базе (он должен хотя бы раз написать боту)."))
+			return
+		}
+	} else {
+		id, perr := strconv.ParseInt(txt, 10, 64)
+		if perr != nil || id <= 0 {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Неверный формат. Введите telegram id или @username."))
+			return
+		}
+		telegramID = id
+		if _, found, _ := storage.GetUserChatIDByTelegramID(b.db, telegramID); !found {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь с таким telegram id не найден в базе (он должен хотя бы раз написать боту)."))
+			return
+		}
+	}
+
+	if err := storage.SetUserBlockedByTelegramID(b.db, telegramID, true); err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось заблокировать (ошибка БД)."))
+		return
+	}
+
+	s.Stage = bStageIdle
+	msg := tgbotapi.NewMessage(m.Chat.ID, fmt.Sprintf("Готово. Пользователь %d заблокирован (blocked=1).", telegramID))
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) handleUnblockInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "" {
+		return
+	}
+
+	if txt == "❌ Отмена" {
+		s.Stage = bStageIdle
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Отменено.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	var telegramID int64
+	var ok bool
+	var err error
+
+	if strings.HasPrefix(txt, "@") {
+		telegramID, ok, err = storage.GetTelegramIDByUsername(b.db, txt)
+		if err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Ошибка поиска по @username."))
+			return
+		}
+		if !ok {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь не найден в базе (он должен хотя бы раз написать боту)."))
+			return
+		}
+	} else {
+		id, perr := strconv.ParseInt(txt, 10, 64)
+		if perr != nil || id <= 0 {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Неверный формат. Введите telegram id или @username."))
+			return
+		}
+		telegramID = id
+		if _, found, _ := storage.GetUserChatIDByTelegramID(b.db, telegramID); !found {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь с таким telegram id не найден в базе (он должен хотя бы раз написать боту)."))
+			return
+		}
+	}
+
+	if err := storage.SetUserBlockedByTelegramID(b.db, telegramID, false); err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось разблокировать (ошибка БД)."))
+		return
+	}
+
+	s.Stage = bStageIdle
+	msg := tgbotapi.NewMessage(m.Chat.ID, fmt.Sprintf("Готово. Пользователь %d разблокирован (blocked=0).", telegramID))
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+// =====================
+// ✉️ Direct message flow
+// =====================
+
+func (b *Bot) handleDirectTargetInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "" {
+		return
+	}
+
+	if txt == "❌ Отмена" {
+		s.Stage = bStageIdle
+		s.DirectUserChatID = 0
+		s.DirectUserRef = ""
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Отменено.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	var chatID int64
+	var ok bool
+	var err error
+
+	if strings.HasPrefix(txt, "@") {
+		chatID, ok, err = storage.GetEligibleUserChatIDByUsername(b.db, txt)
+		if err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Ошибка поиска по @username."))
+			return
+		}
+		if !ok {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь не найден/не подходит (нужен allowed=1 и blocked=0)."))
+			return
+		}
+		s.DirectUserRef = txt
+	} else {
+		id, perr := strconv.ParseInt(txt, 10, 64)
+		if perr != nil || id <= 0 {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Неверный формат. Введите telegram id или @username."))
+			return
+		}
+		chatID, ok, err = storage.GetEligibleUserChatIDByTelegramID(b.db, id)
+		if err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Ошибка поиска по id."))
+			return
+		}
+		if !ok {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Пользователь не найден/не подходит (нужен allowed=1 и blocked=0)."))
+			return
+		}
+		s.DirectUserRef = fmt.Sprintf("id:%d", id)
+	}
+
+	s.DirectUserChatID = chatID
+	s.Stage = bStageAwaitDirectMessage
+
+	msg := tgbotapi.NewMessage(m.Chat.ID, "Ок. Теперь отправьте сообщение/файл/фото для "+s.DirectUserRef+".\nОтмена: «❌ Отмена».")
+	msg.ReplyMarkup = directMsgKeyboard()
+	_, _ = b.send(msg)
+
+}
+
+func (b *Bot) handleDirectMessageSend(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+
+	if txt == "❌ Отмена" {
+		s.Stage = bStageIdle
+		s.DirectUserChatID = 0
+		s.DirectUserRef = ""
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Отменено.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	targetChatID := s.DirectUserChatID
+	if targetChatID == 0 {
+		s.Stage = bStageIdle
+
+		msg := tgbotapi.NewMessage(m.Chat.ID, "Цель не выбрана. Нажмите «✉️ Написать» заново.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	alias := ResponderAlias(b.cfg, m.From)
+	prefix := strings.TrimSpace(alias) + ":\n"
+
+	if m.Document == nil && len(m.Photo) == 0 {
+		if strings.TrimSpace(txt) == "" {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Отправьте текст или файл/фото."))
+			return
+		}
+		out := tgbotapi.NewMessage(targetChatID, prefix+txt)
+		if _, err := b.send(out); err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось отправить пользователю."))
+			return
+		}
+
+	} else if m.Document != nil {
+		doc := tgbotapi.NewDocument(targetChatID, tgbotapi.FileID(m.Document.FileID))
+		cap := strings.TrimSpace(m.Caption)
+		if cap != "" {
+			doc.Caption = prefix + cap
+		} else {
+			doc.Caption = strings.TrimSuffix(prefix, "\n")
+		}
+		if _, err := b.send(doc); err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось отправить документ пользователю."))
+			return
+		}
+
+	} else if len(m.Photo) > 0 {
+		ph := m.Photo[len(m.Photo)-1]
+		p := tgbotapi.NewPhoto(targetChatID, tgbotapi.FileID(ph.FileID))
+		cap := strings.TrimSpace(m.Caption)
+		if cap != "" {
+			p.Caption = prefix + cap
+		} else {
+			p.Caption = strings.TrimSuffix(prefix, "\n")
+		}
+		if _, err := b.send(p); err != nil {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось отправить фото пользователю."))
+			return
+		}
+	}
+
+	done := tgbotapi.NewMessage(m.Chat.ID, "Отправлено пользователю "+s.DirectUserRef+".")
+	done.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(done)
+
+	s.Stage = bStageIdle
+	s.DirectUserChatID = 0
+	s.DirectUserRef = ""
+}
+
+// =====================
+// 📨 Broadcast flow
+// =====================
+
+func (b *Bot) startBroadcastFlow(s *navBroadcastState, chatID int64) {
+	s.stopAlbumTimer()
+	s.Stage = bStageAwaitTemplate
+	s.Payload = nil
+	s.Audience = nil
+
+	msg := tgbotapi.NewMessage(chatID, "Отправьте сообщение, которое нужно разослать, — пользователи получат его копию как есть: "+
+		"с форматированием и ссылками. Можно фото, файл, альбом, видео, аудио, голосовое или стикер.\n"+
+		"Кому отправить — выберете следующим шагом.")
+	msg.ReplyMarkup = directMsgKeyboard()
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) cancelBroadcastFlow(s *navBroadcastState, chatID int64) {
+	s.stopAlbumTimer()
+	s.Stage = bStageIdle
+	s.Payload = nil
+	s.Audience = nil
+
+	msg := tgbotapi.NewMessage(chatID, "Отменено.")
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) captureBroadcastTemplate(s *navBroadcastState, m *tgbotapi.Message) {
+	if strings.TrimSpace(m.Text) == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	kind := templateKind(m)
+	if kind == "" {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Такое сообщение разослать нельзя. Нужен текст, фото, файл, альбом, видео, аудио, голосовое или стикер."))
+		return
+	}
+
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		text = strings.TrimSpace(m.Caption)
+	}
+
+	// альбом приходит отдельными сообщениями с общим media_group_id — собираем до паузы
+	if m.MediaGroupID != "" {
+		if s.Payload == nil || s.Payload.MediaGroupID != m.MediaGroupID {
+			s.stopAlbumTimer()
+			s.Payload = &BroadcastPayload{SourceChatID: m.Chat.ID, Kind: "album", MediaGroupID: m.MediaGroupID}
+		}
+		s.Payload.MessageIDs = append(s.Payload.MessageIDs, m.MessageID)
+		if s.Payload.Text == "" {
+			s.Payload.Text = text
+		}
+		b.waitAlbumEnd(s, m.Chat.ID, int64(m.From.ID), m.MediaGroupID)
+		return
+	}
+
+	s.stopAlbumTimer()
+	payload := &BroadcastPayload{
+		Text:         text,
+		SourceChatID: m.Chat.ID,
+		MessageIDs:   []int{m.MessageID},
+		Kind:         kind,
+	}
+	if m.Document != nil {
+		payload.DocumentFileID = m.Document.FileID
+	}
+	if len(m.Photo) > 0 {
+		payload.PhotoFileID = m.Photo[len(m.Photo)-1].FileID
+	}
+
+	s.Payload = payload
+	s.Audience = nil
+	s.Stage = bStageIdle
+
+	b.askBroadcastAudience(m.Chat.ID)
+}
+
+func (b *Bot) handleScheduleTimeInput(s *navBroadcastState, m *tgbotapi.Message) {
+	if strings.TrimSpace(m.Text) == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+
+	text := strings.TrimSpace(m.Text)
+	if text == "" || s.Payload == nil || s.Audience == nil {
+		return
+	}
+
+	layout := "02.01.2006 15:04"
+	loc := moscowLocation()
+
+	tm, err := time.ParseInLocation(layout, text, loc)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Неверный формат. Пример: 05.12.2025 10:30"))
+		return
+	}
+	if !tm.After(time.Now().In(loc)) {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Время уже прошло. Укажите будущее время."))
+		return
+	}
+
+	sb := &storage.ScheduledBroadcast{
+		Bot:            b.profile.Name,
+		Text:           s.Payload.Text,
+		DocumentFileID: s.Payload.DocumentFileID,
+		PhotoFileID:    s.Payload.PhotoFileID,
+		SourceChatID:   s.Payload.SourceChatID,
+		MessageIDs:     s.Payload.MessageIDs,
+		Kind:           s.Payload.Kind,
+		Audience:       s.Audience.String(),
+		RunAt:          tm,
+	}
+	if m.From != nil {
+		sb.CreatedBy = int64(m.From.ID)
+	}
+	if _, err := storage.CreateScheduledBroadcast(b.db, sb); err != nil {
+		b.logf("CreateScheduledBroadcast error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось запланировать рассылку (ошибка БД)."))
+		return
+	}
+	s.Stage = bStageIdle
+	s.Payload = nil
+	a := *s.Audience
+	s.Audience = nil
+
+	text = fmt.Sprintf("Ок, рассылка #%d запланирована на %s.\nАудитория: %s", sb.ID, tm.Format("02.01.2006 15:04"), audienceTitle(a))
+	if sb.SourceChatID != 0 {
+		text += "\nНе удаляйте сообщение-шаблон из этого чата до отправки — рассылается его копия."
+	}
+	msg := tgbotapi.NewMessage(m.Chat.ID, text)
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+// broadcastResult — итог рассылки; подробности по получателям лежат в broadcast_deliveries.
+type broadcastResult struct {
+	ID     int64 // 0 — не удалось записать в журнал
+	Sent   int
+	Failed int
+}
+
+// broadcastSource — откуда рассылка: кто создал и, если не «Отправить сейчас», какая запись её запустила.
+type broadcastSource struct {
+	CreatedBy   int64
+	ScheduledID int64
+	RecurringID int64
+}
+
+// broadcastToAudience рассылает payload выбранной аудитории и пишет журнал доставки.
+// Получателей считаем в момент отправки (к запланированному времени список мог измениться).
+func (b *Bot) broadcastToAudience(ctx context.Context, payload *BroadcastPayload, a storage.Audience, src broadcastSource) broadcastResult {
+	var res broadcastResult
+	if payload == nil {
+		return res
+	}
+
+	chatIDs, _, err := b.audienceChatIDs(a)
+	if err != nil {
+		b.logf("audienceChatIDs error: %v", err)
+		return res
+	}
+
+	rec := &storage.Broadcast{
+		Bot:            b.profile.Name,
+		ScheduledID:    src.ScheduledID,
+		RecurringID:    src.RecurringID,
+		Text:           payload.Text,
+		DocumentFileID: payload.DocumentFileID,
+		PhotoFileID:    payload.PhotoFileID,
+		SourceChatID:   payload.SourceChatID,
+		MessageIDs:     payload.MessageIDs,
+		Kind:           payload.Kind,
+		Audience:       a.String(),
+		CreatedBy:      src.CreatedBy,
+		Total:          len(chatIDs),
+	}
+	if _, err := storage.CreateBroadcast(b.db, rec); err != nil {
+		// журнал — не повод не отправлять
+		b.logf("CreateBroadcast error: %v", err)
+	}
+	res.ID = rec.ID
+
+	for _, cid := range chatIDs {
+		// остановка процесса: прерываем рассылку, отправленное уже не вернуть
+		if ctx.Err() != nil {
+			b.logf("broadcast #%d interrupted after %d users: %v", res.ID, res.Sent+res.Failed, ctx.Err())
+			break
+		}
+
+		msgID, err := b.sendBroadcastPayload(ctx, cid, payload)
+		errText := ""
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			b.logf("broadcast #%d to %d error: %v", res.ID, cid, err)
+			errText = err.Error()
+			res.Failed++
+		} else {
+			res.Sent++
+		}
+
+		if res.ID != 0 {
+			if err := storage.AddBroadcastDelivery(b.db, res.ID, cid, msgID, errText); err != nil {
+				b.logf("AddBroadcastDelivery error: %v", err)
+			}
+		}
+	}
+
+	if res.ID != 0 {
+		if err := storage.FinishBroadcast(b.db, res.ID, res.Sent, res.Failed); err != nil {
+			b.logf("FinishBroadcast error: %v", err)
+		}
+	}
+	return res
+}
+
+// sendBroadcastPayload отправляет рассылку одному получателю и возвращает id основного сообщения.
+func (b *Bot) sendBroadcastPayload(ctx context.Context, cid int64, payload *BroadcastPayload) (int, error) {
+	if payload.SourceChatID != 0 && len(payload.MessageIDs) > 0 {
+		return b.copyBroadcastPayload(ctx, cid, payload)
+	}
+
+	text := strings.TrimSpace(payload.Text)
+
+	const captionLimit = 1024
+	caption := text
+	extraText := ""
+	if len([]rune(caption)) > captionLimit {
+		r := []rune(caption)
+		caption = string(r[:captionLimit-3]) + "..."
+		extraText = text
+	}
+
+	var c tgbotapi.Chattable
+	switch {
+	case payload.DocumentFileID != "":
+		doc := tgbotapi.NewDocument(cid, tgbotapi.FileID(payload.DocumentFileID))
+		doc.Caption = caption
+		c = doc
+	case payload.PhotoFileID != "":
+		ph := tgbotapi.NewPhoto(cid, tgbotapi.FileID(payload.PhotoFileID))
+		ph.Caption = caption
+		c = ph
+	case text != "":
+		c = tgbotapi.NewMessage(cid, text)
+		extraText = ""
+	default:
+		return 0, fmt.Errorf("empty broadcast payload")
+	}
+
+	sent, err := b.out.Send(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+	if extraText != "" {
+		_, _ = b.out.Send(ctx, tgbotapi.NewMessage(cid, extraText))
+	}
+	return sent.MessageID, nil
+}
+
+// broadcastDoneText: «Рассылка #N отправлена K пользователям» + сколько не дошло.
+func broadcastDoneText(prefix string, res broadcastResult) string {
+	text := fmt.Sprintf("%s отправлена %d пользователям.", prefix, res.Sent)
+	if res.Failed > 0 {
+		text += fmt.Sprintf("\nНе доставлено: %d.", res.Failed)
+	}
+	if res.ID != 0 {
+		text += fmt.Sprintf("\nОтчёт: /report %d", res.ID)
+	}
+	return text
+}
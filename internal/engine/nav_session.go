@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// через сколько бездействия незавершённый шаг навигатора сбрасывается в idle
+const navSessionTimeout = 15 * time.Minute
+
+// navSessionKey: у каждого сотрудника в каждом навигаторском чате своя сессия,
+// чтобы «✉️ Написать» одного не перетирал «📨 Рассылка» другого.
+type navSessionKey struct {
+	ChatID int64
+	UserID int64
+}
+
+type navSession struct {
+	mu        sync.Mutex
+	state     navBroadcastState
+	touchedAt time.Time
+}
+
+// lockNavSession возвращает состояние сессии (chatID, userID), захваченное под мьютексом.
+// Вызывающий обязан вызвать unlock. Брошенные сессии по таймауту сбрасываются в idle.
+func (b *Bot) lockNavSession(chatID, userID int64) (*navBroadcastState, func()) {
+	key := navSessionKey{ChatID: chatID, UserID: userID}
+	now := time.Now()
+
+	b.navMu.Lock()
+	sess := b.navSessions[key]
+	if sess == nil {
+		sess = &navSession{state: navBroadcastState{Stage: bStageIdle}, touchedAt: now}
+		b.navSessions[key] = sess
+	}
+	b.pruneNavSessionsLocked(now, key)
+	b.navMu.Unlock()
+
+	sess.mu.Lock()
+	if now.Sub(sess.touchedAt) > navSessionTimeout {
+		sess.state = navBroadcastState{Stage: bStageIdle}
+	}
+
+	return &sess.state, func() {
+		sess.touchedAt = time.Now()
+		sess.mu.Unlock()
+	}
+}
+
+// pruneNavSessionsLocked удаляет давно брошенные сессии (кроме текущей). navMu должен быть захвачен.
+func (b *Bot) pruneNavSessionsLocked(now time.Time, keep navSessionKey) {
+	for k, sess := range b.navSessions {
+		if k == keep {
+			continue
+		}
+		if !sess.mu.TryLock() {
+			continue
+		}
+		expired := now.Sub(sess.touchedAt) > navSessionTimeout
+		sess.mu.Unlock()
+		if expired {
+			delete(b.navSessions, k)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var weekdayNamesRu = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+// formatDayRu: "12.06.2026 (пт)".
+func formatDayRu(t time.Time) string {
+	t = t.In(moscowLocation())
+	return t.Format("02.01.2006") + " (" + weekdayNamesRu[t.Weekday()] + ")"
+}
+
+// handleCalendarCommand: /calendar — что думает бот о сегодняшнем дне, /calendar reload — перечитать файл.
+func (b *Bot) handleCalendarCommand(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	arg := strings.TrimSpace(m.CommandArguments())
+
+	switch arg {
+	case "":
+	case "reload":
+		// ✅ перечитывать файл могут только ответственные
+		if m.From == nil || !b.cfg.ResponderIDs[int64(m.From.ID)] {
+			_, _ = b.send(tgbotapi.NewMessage(chatID, "Перечитать календарь могут только ответственные."))
+			return
+		}
+		if err := b.cal.Reload(); err != nil {
+			b.logf("calendar reload error: %v", err)
+			_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось перечитать календарь, оставлены прежние данные:\n"+err.Error()))
+			return
+		}
+		b.logf("production calendar reloaded from %s", b.cal.Path())
+	default:
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Формат: /calendar или /calendar reload"))
+		return
+	}
+
+	_, _ = b.send(tgbotapi.NewMessage(chatID, b.calendarSummary(time.Now())))
+}
+
+func (b *Bot) calendarSummary(now time.Time) string {
+	loadedAt, years := b.cal.Info()
+
+	yearsText := "нет данных — только выходные сб/вс"
+	if len(years) > 0 {
+		parts := make([]string, 0, len(years))
+		for _, y := range years {
+			parts = append(parts, strconv.Itoa(y))
+		}
+		yearsText = strings.Join(parts, ", ")
+	}
+
+	lines := []string{
+		"📅 Производственный календарь",
+		"Файл: " + b.cal.Path(),
+		"Загружен: " + loadedAt.In(moscowLocation()).Format("02.01.2006 15:04:05"),
+		"Годы: " + yearsText,
+		"",
+	}
+
+	today := "Сегодня " + formatDayRu(now) + ": "
+	if d, ok := b.cal.Deadline(now); ok {
+		today += "рабочий день"
+		if b.cal.IsShortDay(now) {
+			today += " (сокращённый)"
+		}
+		today += ", приём заявок до " + d.Format("15:04")
+		if now.After(d) {
+			today += " — уже закончен"
+		}
+	} else {
+		today += "нерабочий день"
+	}
+	lines = append(lines, today)
+
+	if next := b.cal.NextDeadline(now); !next.IsZero() {
+		lines = append(lines, fmt.Sprintf("Ближайший конец приёма: %s %s", formatDayRu(next), next.Format("15:04")))
+	}
+	lines = append(lines, "Следующий рабочий день: "+formatDayRu(b.cal.NextWorkingDay(now)))
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,101 @@
+package engine
+
+import "TGBOT2/internal/config"
+
+// Profile описывает конкретного бота: куда маршрутизировать сообщения и какие функции включены.
+// Сам движок один для всех ботов — отличаются только профили.
+type Profile struct {
+	Name  string // "bot1" | "bot2" | "bot3" — ключ в БД и префикс логов
+	Title string // подпись в панели навигатора
+	Token string
+
+	NavigatorChatID int64
+	// бухгалтерские группы. С CompanyPicker индекс = номер компании - 1,
+	// без него сообщение пользователя уходит во все группы списка.
+	AccountingChatIDs []int64
+	// чат подтверждения заявок (нужен для Applications)
+	ApprovalChatID int64
+
+	CompanyPicker bool // выбор компании перед отправкой документов (bot1)
+	Applications  bool // мастер «📝 Составить заявку» + чат подтверждения (bot3)
+	BlockUnblock  bool // «🚫 Блокировка» / «✅ Разблокировать» у навигатора
+}
+
+func ProfileBot1(cfg *config.Config) Profile {
+	return Profile{
+		Name:            "bot1",
+		Title:           "bot1",
+		Token:           cfg.Bot1Token,
+		NavigatorChatID: cfg.Bot1NavigatorChatID,
+		AccountingChatIDs: []int64{
+			cfg.Accounting1ChatID,
+			cfg.Accounting2ChatID,
+			cfg.Accounting3ChatID,
+			cfg.Accounting4ChatID,
+		},
+		CompanyPicker: true,
+		BlockUnblock:  true,
+	}
+}
+
+func ProfileBot2(cfg *config.Config) Profile {
+	return Profile{
+		Name:              "bot2",
+		Title:             "bot2",
+		Token:             cfg.Bot2Token,
+		NavigatorChatID:   cfg.Bot2NavigatorChatID,
+		AccountingChatIDs: []int64{cfg.Bot2AccountingChatID},
+		BlockUnblock:      true,
+	}
+}
+
+func ProfileBot3(cfg *config.Config) Profile {
+	return Profile{
+		Name:            "bot3",
+		Title:           "bot3",
+		Token:           cfg.Bot3Token,
+		NavigatorChatID: cfg.Bot3NavigatorChatID,
+		ApprovalChatID:  cfg.Bot3ApprovalChatID,
+		Applications:    true,
+		BlockUnblock:    true,
+	}
+}
+
+// ProfileByName: профиль по имени из BOTD_BOTS.
+func ProfileByName(cfg *config.Config, name string) (Profile, bool) {
+	switch name {
+	case "bot1":
+		return ProfileBot1(cfg), true
+	case "bot2":
+		return ProfileBot2(cfg), true
+	case "bot3":
+		return ProfileBot3(cfg), true
+	default:
+		return Profile{}, false
+	}
+}
+
+// AllProfiles: все известные боты в порядке bot1, bot2, bot3.
+func AllProfiles(cfg *config.Config) []Profile {
+	return []Profile{ProfileBot1(cfg), ProfileBot2(cfg), ProfileBot3(cfg)}
+}
+
+func (p Profile) isAccountingChat(chatID int64) bool {
+	if chatID == 0 {
+		return false
+	}
+	for _, id := range p.AccountingChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// isServiceChat: служебные чаты бота — туда не шлём рассылки.
+func (p Profile) isServiceChat(chatID int64) bool {
+	if chatID == 0 {
+		return false
+	}
+	return chatID == p.NavigatorChatID || chatID == p.ApprovalChatID || p.isAccountingChat(chatID)
+}
@@ -0,0 +1,475 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/cron"
+	"TGBOT2/internal/storage"
+)
+
+// встроенное напоминание о конце приёма заявок (раньше было зашито в каждый cmd/botN)
+const (
+	deadlineReminderKey  = "deadline_reminder"
+	deadlineReminderSpec = "@deadline-15m"
+	deadlineReminderText = "Уважаемые партнёры, через 15 минут заканчивается приём заявок"
+
+	// так напоминание заводилось до производственного календаря — такие строки переводим на @deadline
+	legacyDeadlineReminderSpec = "35 15 * * *"
+)
+
+// deadlineSpecPrefix: "@deadline-15m" — за 15 минут до конца приёма заявок по производственному
+// календарю, только в рабочие дни (на сокращённых днях — раньше).
+const deadlineSpecPrefix = "@deadline"
+
+// если бот лежал и запуск опоздал больше чем на это время — пропускаем его, а не шлём с опозданием
+const recurringGrace = 5 * time.Minute
+
+const recurringSpecHelp = "Когда отправлять (по Москве)? Например:\n" +
+	"• ежедневно 10:00\n" +
+	"• по будням 15:35\n" +
+	"• по выходным 12:00\n" +
+	"• пн,ср,пт 09:30\n" +
+	"• 1 числа 10:00\n" +
+	"• за 15 минут до конца приёма (рабочие дни по производственному календарю)\n" +
+	"или cron из 5 полей: 35 15 * * 1-5\n" +
+	"Отмена: «❌ Отмена»."
+
+// ensureBuiltinRecurring заводит в БД встроенные регулярные рассылки бота (один раз).
+func (b *Bot) ensureBuiltinRecurring() {
+	r := &storage.RecurringBroadcast{
+		Bot:      b.profile.Name,
+		Key:      deadlineReminderKey,
+		Spec:     deadlineReminderSpec,
+		Text:     deadlineReminderText,
+		Kind:     "text",
+		Audience: storage.Audience{Kind: storage.AudienceAllowed}.String(),
+	}
+	if next, err := b.recurringNext(r.Spec, time.Now()); err == nil {
+		r.NextRunAt = next
+	}
+	created, err := storage.EnsureRecurringBroadcast(b.db, r)
+	if err != nil {
+		b.logf("EnsureRecurringBroadcast error: %v", err)
+		return
+	}
+	if created {
+		b.logf("recurring broadcast #%d (%s) created", r.ID, r.Key)
+		return
+	}
+
+	// напоминание, заведённое до календаря (каждый день в 15:35), — на рабочие дни по календарю
+	migrated, err := storage.ReplaceBuiltinRecurringSpec(b.db, b.profile.Name, r.Key, legacyDeadlineReminderSpec, r.Spec, r.NextRunAt)
+	if err != nil {
+		b.logf("ReplaceBuiltinRecurringSpec error: %v", err)
+		return
+	}
+	if migrated {
+		b.logf("recurring broadcast %s now follows the production calendar (%s)", r.Key, r.Spec)
+	}
+}
+
+// recurringNext — следующий запуск строго после after: "@deadline-15m" считается
+// по производственному календарю, остальное — cron по Москве.
+func (b *Bot) recurringNext(spec string, after time.Time) (time.Time, error) {
+	if strings.HasPrefix(spec, deadlineSpecPrefix) {
+		offset, err := parseDeadlineOffset(spec)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// ищем ближайший дедлайн d, у которого d+offset > after
+		d := b.cal.NextDeadline(after.Add(-offset))
+		if d.IsZero() {
+			return time.Time{}, nil
+		}
+		return d.Add(offset), nil
+	}
+
+	sched, err := cron.Parse(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(after.In(moscowLocation())), nil
+}
+
+// parseDeadlineOffset: "@deadline" -> 0, "@deadline-15m" -> -15m, "@deadline+1h" -> +1h.
+func parseDeadlineOffset(spec string) (time.Duration, error) {
+	rest := strings.TrimPrefix(spec, deadlineSpecPrefix)
+	if rest == "" {
+		return 0, nil
+	}
+	if rest[0] != '-' && rest[0] != '+' {
+		return 0, fmt.Errorf("bad deadline spec %q", spec)
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("bad deadline spec %q: %w", spec, err)
+	}
+	return d, nil
+}
+
+// RunDueRecurringBroadcasts отправляет регулярные рассылки, время которых наступило.
+func (b *Bot) RunDueRecurringBroadcasts(ctx context.Context) {
+	loc := moscowLocation()
+	now := time.Now().In(loc)
+
+	due, err := storage.ListDueRecurringBroadcasts(b.db, b.profile.Name, now)
+	if err != nil {
+		b.logf("ListDueRecurringBroadcasts error: %v", err)
+		return
+	}
+
+	for _, r := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		next, err := b.recurringNext(r.Spec, now)
+		if err != nil {
+			b.logf("recurring broadcast #%d: %v", r.ID, err)
+		}
+
+		late := now.Sub(r.NextRunAt) > recurringGrace
+		run := err == nil && !late
+
+		// сначала двигаем next_run_at: из двух процессов отправит только тот, кто успел
+		claimed, cerr := storage.ClaimRecurringRun(b.db, r.ID, r.NextRunAt, next, run)
+		if cerr != nil {
+			b.logf("ClaimRecurringRun error: %v", cerr)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if late {
+			b.logf("recurring broadcast #%d skipped: missed run at %s", r.ID, r.NextRunAt.In(loc).Format("02.01.2006 15:04"))
+		}
+		if !run {
+			continue
+		}
+
+		a, err := storage.ParseAudience(r.Audience)
+		if err != nil {
+			b.logf("recurring broadcast #%d: %v", r.ID, err)
+			continue
+		}
+		payload := &BroadcastPayload{
+			Text:         r.Text,
+			SourceChatID: r.SourceChatID,
+			MessageIDs:   r.MessageIDs,
+			Kind:         r.Kind,
+		}
+		res := b.broadcastToAudience(ctx, payload, a, broadcastSource{CreatedBy: r.CreatedBy, RecurringID: r.ID})
+		b.logf("recurring broadcast #%d sent to %d users, failed %d", r.ID, res.Sent, res.Failed)
+	}
+}
+
+// =====================
+// Панель навигатора
+// =====================
+
+func (b *Bot) handleRecurringSpecInput(s *navBroadcastState, m *tgbotapi.Message) {
+	txt := strings.TrimSpace(m.Text)
+	if txt == "❌ Отмена" {
+		b.cancelBroadcastFlow(s, m.Chat.ID)
+		return
+	}
+	if txt == "" {
+		return
+	}
+
+	spec, err := parseRecurringSpec(txt)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не понял расписание.\n\n"+recurringSpecHelp))
+		return
+	}
+	next, err := b.recurringNext(spec, time.Now())
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Неверное расписание: "+err.Error()))
+		return
+	}
+	if next.IsZero() {
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "По такому расписанию рассылка никогда не сработает. Укажите другое."))
+		return
+	}
+
+	r := &storage.RecurringBroadcast{
+		Bot:          b.profile.Name,
+		Spec:         spec,
+		Text:         s.Payload.Text,
+		SourceChatID: s.Payload.SourceChatID,
+		MessageIDs:   s.Payload.MessageIDs,
+		Kind:         s.Payload.Kind,
+		Audience:     s.Audience.String(),
+		NextRunAt:    next,
+	}
+	if m.From != nil {
+		r.CreatedBy = int64(m.From.ID)
+	}
+	if _, err := storage.CreateRecurringBroadcast(b.db, r); err != nil {
+		b.logf("CreateRecurringBroadcast error: %v", err)
+		_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Не удалось сохранить регулярную рассылку (ошибка БД)."))
+		return
+	}
+
+	a := *s.Audience
+	s.Stage = bStageIdle
+	s.Payload = nil
+	s.Audience = nil
+
+	text := fmt.Sprintf("Ок, регулярная рассылка #%d: %s.\nАудитория: %s\nБлижайшая отправка: %s",
+		r.ID, describeRecurringSpec(spec), audienceTitle(a), next.In(moscowLocation()).Format("02.01.2006 15:04"))
+	if r.SourceChatID != 0 {
+		text += "\nНе удаляйте сообщение-шаблон из этого чата — рассылается его копия."
+	}
+	msg := tgbotapi.NewMessage(m.Chat.ID, text)
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) sendRecurringList(chatID int64) {
+	list, err := storage.ListRecurringBroadcasts(b.db, b.profile.Name)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить список регулярных рассылок (ошибка БД)."))
+		return
+	}
+	if len(list) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "Регулярных рассылок нет.\nСоздать: «📨 Рассылка» → аудитория → «🔁 Регулярно».")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	loc := moscowLocation()
+	lines := []string{"🔁 Регулярные рассылки:"}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, r := range list {
+		status := "⏸ на паузе"
+		if !r.Paused {
+			status = "следующая: —"
+			if !r.NextRunAt.IsZero() {
+				status = "следующая: " + r.NextRunAt.In(loc).Format("02.01.2006 15:04")
+			}
+		}
+		lines = append(lines, fmt.Sprintf("#%d — %s — %s — %s\n   %s", r.ID, describeRecurringSpec(r.Spec),
+			audienceTitleSpec(r.Audience), scheduledSummary(storage.ScheduledBroadcast{
+				Text:       r.Text,
+				MessageIDs: r.MessageIDs,
+				Kind:       r.Kind,
+			}), status))
+
+		toggle := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("⏸ Пауза #%d", r.ID), fmt.Sprintf("rec_pause:%d", r.ID))
+		if r.Paused {
+			toggle = tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("▶️ Включить #%d", r.ID), fmt.Sprintf("rec_resume:%d", r.ID))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			toggle,
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 Удалить #%d", r.ID), fmt.Sprintf("rec_delete:%d", r.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+// handleRecurringCallback: rec_pause:ID / rec_resume:ID / rec_delete:ID
+func (b *Bot) handleRecurringCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.From == nil || !b.cfg.ResponderIDs[int64(cq.From.ID)] {
+		return
+	}
+
+	action, idStr, _ := strings.Cut(cq.Data, ":")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return
+	}
+	chatID := cq.Message.Chat.ID
+
+	r, ok, err := storage.GetRecurringBroadcast(b.db, b.profile.Name, id)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Ошибка БД."))
+		return
+	}
+	if !ok {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Регулярная рассылка #%d не найдена.", id)))
+		return
+	}
+
+	var text string
+	switch action {
+	case "rec_pause":
+		ok, err = storage.SetRecurringPaused(b.db, b.profile.Name, id, true, time.Time{})
+		text = fmt.Sprintf("Регулярная рассылка #%d на паузе.", id)
+
+	case "rec_resume":
+		next, perr := b.recurringNext(r.Spec, time.Now())
+		if perr != nil {
+			_, _ = b.send(tgbotapi.NewMessage(chatID, "Неверное расписание: "+perr.Error()))
+			return
+		}
+		ok, err = storage.SetRecurringPaused(b.db, b.profile.Name, id, false, next)
+		text = fmt.Sprintf("Регулярная рассылка #%d включена. Ближайшая отправка: %s", id, next.In(moscowLocation()).Format("02.01.2006 15:04"))
+
+	case "rec_delete":
+		ok, err = storage.DeleteRecurringBroadcast(b.db, b.profile.Name, id)
+		text = fmt.Sprintf("Регулярная рассылка #%d удалена.", id)
+
+	default:
+		return
+	}
+	if err != nil {
+		b.logf("recurring %s #%d error: %v", action, id, err)
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось изменить рассылку (ошибка БД)."))
+		return
+	}
+	if !ok {
+		text = fmt.Sprintf("Регулярная рассылка #%d уже удалена.", id)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+// =====================
+// Расписание словами
+// =====================
+
+var (
+	reSpecTime           = regexp.MustCompile(`^(.*?)\s*(?:в\s+)?(\d{1,2})[:.](\d{2})$`)
+	reSpecBeforeDeadline = regexp.MustCompile(`^за\s+(\d{1,3})\s+мин\S*\s+до\s+(?:конца\s+при[её]ма|дедлайна)`)
+	reSpecDay            = regexp.MustCompile(`^(?:каждое\s+)?(\d{1,2})(?:-?го)?\s+(?:числа|число)(?:\s+каждого\s+месяца)?$`)
+	weekdayCron          = map[string]string{"пн": "1", "вт": "2", "ср": "3", "чт": "4", "пт": "5", "сб": "6", "вс": "0"}
+)
+
+// parseRecurringSpec: «по будням 15:35», «1 числа 10:00», «пн,ср 09:00» или cron из 5 полей -> cron.
+func parseRecurringSpec(text string) (string, error) {
+	t := strings.ToLower(strings.Join(strings.Fields(text), " "))
+
+	if strings.HasPrefix(t, deadlineSpecPrefix) {
+		if _, err := parseDeadlineOffset(t); err != nil {
+			return "", err
+		}
+		return t, nil
+	}
+	if m := reSpecBeforeDeadline.FindStringSubmatch(t); m != nil {
+		return deadlineSpecPrefix + "-" + m[1] + "m", nil
+	}
+
+	if len(strings.Fields(t)) == 5 {
+		if _, err := cron.Parse(t); err == nil {
+			return t, nil
+		}
+	}
+
+	m := reSpecTime.FindStringSubmatch(t)
+	if m == nil {
+		return "", fmt.Errorf("no time in %q", text)
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	if hour > 23 || minute > 59 {
+		return "", fmt.Errorf("bad time in %q", text)
+	}
+	days := strings.TrimSpace(m[1])
+
+	dom, dow := "*", "*"
+	switch days {
+	case "", "ежедневно", "каждый день":
+	case "по будням", "будни", "в будни", "по рабочим дням":
+		dow = "1-5"
+	case "по выходным", "выходные", "в выходные":
+		dow = "0,6"
+	default:
+		if dm := reSpecDay.FindStringSubmatch(days); dm != nil {
+			d, _ := strconv.Atoi(dm[1])
+			if d < 1 || d > 31 {
+				return "", fmt.Errorf("bad day in %q", text)
+			}
+			dom = dm[1]
+			break
+		}
+
+		var list []string
+		for _, w := range strings.FieldsFunc(days, func(r rune) bool { return r == ',' || r == ' ' }) {
+			n, ok := weekdayCron[w]
+			if !ok {
+				return "", fmt.Errorf("bad days %q", days)
+			}
+			list = append(list, n)
+		}
+		if len(list) == 0 {
+			return "", fmt.Errorf("bad days %q", days)
+		}
+		dow = strings.Join(list, ",")
+	}
+
+	return fmt.Sprintf("%d %d %s * %s", minute, hour, dom, dow), nil
+}
+
+// describeRecurringSpec: cron -> «по будням 15:35» для простых расписаний, иначе сам cron.
+func describeRecurringSpec(spec string) string {
+	if strings.HasPrefix(spec, deadlineSpecPrefix) {
+		offset, err := parseDeadlineOffset(spec)
+		switch {
+		case err != nil:
+			return spec
+		case offset == 0:
+			return "рабочие дни, в момент окончания приёма заявок"
+		case offset < 0:
+			return fmt.Sprintf("рабочие дни, за %s до конца приёма заявок", humanDuration(-offset))
+		default:
+			return fmt.Sprintf("рабочие дни, через %s после конца приёма заявок", humanDuration(offset))
+		}
+	}
+
+	f := strings.Fields(spec)
+	if len(f) != 5 || f[3] != "*" {
+		return "cron " + spec
+	}
+	minute, err1 := strconv.Atoi(f[0])
+	hour, err2 := strconv.Atoi(f[1])
+	if err1 != nil || err2 != nil {
+		return "cron " + spec
+	}
+	at := fmt.Sprintf("%02d:%02d", hour, minute)
+
+	switch {
+	case f[2] == "*" && f[4] == "*":
+		return "ежедневно " + at
+	case f[2] == "*" && f[4] == "1-5":
+		return "по будням " + at
+	case f[2] == "*" && (f[4] == "0,6" || f[4] == "6,0"):
+		return "по выходным " + at
+	case f[4] == "*":
+		if _, err := strconv.Atoi(f[2]); err == nil {
+			return f[2] + " числа " + at
+		}
+	case f[2] == "*":
+		names := map[string]string{"0": "вс", "1": "пн", "2": "вт", "3": "ср", "4": "чт", "5": "пт", "6": "сб", "7": "вс"}
+		var out []string
+		for _, d := range strings.Split(f[4], ",") {
+			n, ok := names[d]
+			if !ok {
+				return "cron " + spec
+			}
+			out = append(out, n)
+		}
+		return strings.Join(out, ",") + " " + at
+	}
+	return "cron " + spec
+}
+
+func humanDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%d ч", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%d мин", int(d/time.Minute))
+}
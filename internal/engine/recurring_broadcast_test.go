@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurringSpec(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "по будням 15:35", want: "35 15 * * 1-5"},
+		{in: "ежедневно в 9:05", want: "5 9 * * *"},
+		{in: "10.30", want: "30 10 * * *"},
+		{in: "по выходным 12:00", want: "0 12 * * 0,6"},
+		{in: "1 числа 10:00", want: "0 10 1 * *"},
+		{in: "каждое 15-го числа каждого месяца 08:00", want: "0 8 15 * *"},
+		{in: "пн,ср 09:00", want: "0 9 * * 1,3"},
+		{in: "0 9 * * 1-5", want: "0 9 * * 1-5"},
+		{in: "по будням 25:00", wantErr: true},
+		{in: "32 числа 10:00", wantErr: true},
+		{in: "пн,xx 09:00", wantErr: true},
+		{in: "когда-нибудь", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRecurringSpec(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRecurringSpec(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseRecurringSpec(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDescribeRecurringSpec(t *testing.T) {
+	tests := map[string]string{
+		"35 15 * * 1-5": "по будням 15:35",
+		"0 12 * * 0,6":  "по выходным 12:00",
+		"5 9 * * *":     "ежедневно 09:05",
+		"0 10 1 * *":    "1 числа 10:00",
+		"0 9 * * 1,3":   "пн,ср 09:00",
+		"*/5 * * * *":   "cron */5 * * * *",
+	}
+	for spec, want := range tests {
+		if got := describeRecurringSpec(spec); got != want {
+			t.Errorf("describeRecurringSpec(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestDeadlineSpecs(t *testing.T) {
+	parse := map[string]string{
+		"за 15 минут до конца приёма": "@deadline-15m",
+		"за 30 мин до дедлайна":       "@deadline-30m",
+		"@deadline":                   "@deadline",
+		"@deadline+1h":                "@deadline+1h",
+	}
+	for in, want := range parse {
+		if got, err := parseRecurringSpec(in); err != nil || got != want {
+			t.Errorf("parseRecurringSpec(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"@deadline15m", "@deadline-15x"} {
+		if got, err := parseRecurringSpec(in); err == nil {
+			t.Errorf("parseRecurringSpec(%q) = %q, want error", in, got)
+		}
+	}
+
+	describe := map[string]string{
+		"@deadline":     "рабочие дни, в момент окончания приёма заявок",
+		"@deadline-15m": "рабочие дни, за 15 мин до конца приёма заявок",
+	}
+	for spec, want := range describe {
+		if got := describeRecurringSpec(spec); got != want {
+			t.Errorf("describeRecurringSpec(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestRecurringNextDeadline(t *testing.T) {
+	b := testIntakeBot(t, "")
+
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{deadlineReminderSpec, msk("2026-10-19", "10:00"), msk("2026-10-19", "15:35")},
+		{deadlineReminderSpec, msk("2026-10-19", "15:35"), msk("2026-10-20", "15:35")},
+		// сокращённый день — напоминание сдвигается вместе с концом приёма
+		{deadlineReminderSpec, msk("2026-11-02", "16:00"), msk("2026-11-03", "14:35")},
+		// праздник пропускаем
+		{deadlineReminderSpec, msk("2026-11-03", "15:00"), msk("2026-11-05", "15:35")},
+		// напоминание уже прошло, но до дедлайна ещё есть время — следующее завтра
+		{deadlineReminderSpec, msk("2026-10-19", "15:40"), msk("2026-10-20", "15:35")},
+		{"@deadline+1h", msk("2026-10-19", "16:00"), msk("2026-10-19", "16:50")},
+		// обычный cron — без календаря
+		{"0 10 * * *", msk("2026-11-03", "11:00"), msk("2026-11-04", "10:00")},
+	}
+	for _, tt := range tests {
+		got, err := b.recurringNext(tt.spec, tt.after)
+		if err != nil {
+			t.Fatalf("recurringNext(%q): %v", tt.spec, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("recurringNext(%q, %s) = %s, want %s", tt.spec, tt.after.Format("02.01 15:04"), got.Format("02.01 15:04"), tt.want.Format("02.01 15:04"))
+		}
+	}
+}
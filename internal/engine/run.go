@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// long polling: телеграм держит getUpdates до pollTimeout. Нет ответа и через pollStallAfter —
+// запрос считаем зависшим (полуоткрытое соединение, у HTTP-клиента tgbotapi нет таймаута).
+var (
+	pollTimeout    = 60 * time.Second
+	pollStallAfter = 2*pollTimeout + 10*time.Second
+)
+
+// Run крутит long polling до отмены ctx. getUpdates зовём сами, а не через GetUpdatesChan:
+// тот молча повторяет ошибки и не замечает зависший запрос. Ошибка — повтор с нарастающей паузой,
+// зависший запрос бросаем и переподключаемся.
+// Возвращается только после того, как все принятые апдейты обработаны.
+func (b *Bot) Run(ctx context.Context) {
+	hctx, cancel := b.handlerContext(ctx)
+	defer cancel()
+	// фоновые задачи запускают обработчики — ждём их после очереди апдейтов
+	defer b.bg.Wait()
+
+	d := newDispatcher(hctx, b, b.cfg.UpdateWorkers)
+	defer d.Wait()
+
+	// если раньше бот работал через webhook — getUpdates без удаления вебхука не отдаст апдейты
+	if _, err := b.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		b.logf("deleteWebhook error: %v", err)
+	}
+
+	offset := 0
+	backoff := time.Second
+	for {
+		updates, err := b.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			b.logf("getUpdates: %v; retry in %s", err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+		backoff = time.Second
+
+		for _, upd := range updates {
+			if upd.UpdateID >= offset {
+				offset = upd.UpdateID + 1
+			}
+			d.Dispatch(upd)
+		}
+	}
+}
+
+// getUpdates — один long poll, который не ждёт дольше pollStallAfter и отпускает по отмене ctx.
+// Брошенный запрос дорабатывает в фоне; его апдейты offset'ом не подтверждены и придут следующему.
+func (b *Bot) getUpdates(ctx context.Context, offset int) ([]tgbotapi.Update, error) {
+	u := tgbotapi.NewUpdate(offset)
+	u.Timeout = int(pollTimeout / time.Second)
+
+	type result struct {
+		updates []tgbotapi.Update
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		updates, err := b.bot.GetUpdates(u)
+		done <- result{updates, err}
+	}()
+
+	stall := time.NewTimer(pollStallAfter)
+	defer stall.Stop()
+
+	select {
+	case r := <-done:
+		return r.updates, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-stall.C:
+		return nil, fmt.Errorf("no response in %s, reconnecting", pollStallAfter)
+	}
+}
+
+// safeHandleUpdate: паника в обработчике одного апдейта не должна ронять весь бот.
+func (b *Bot) safeHandleUpdate(ctx context.Context, upd tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logf("panic in update %d: %v\n%s", upd.UpdateID, r, debug.Stack())
+		}
+	}()
+	b.HandleUpdate(ctx, upd)
+}
+
+// handlerContext: контекст для обработчиков. После отмены ctx (SIGTERM) начатые обработчики
+// ещё ShutdownTimeout доделывают работу, потом их контекст отменяется (soffice, запросы, рассылки).
+func (b *Bot) handlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	hctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(b.cfg.ShutdownTimeout, cancel)
+	})
+	return hctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Drain ждёт завершения горутин бота, но не дольше timeout. false — не дождались.
+func Drain(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
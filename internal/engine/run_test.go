@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeTelegram: getMe отвечает сразу, первый getUpdates виснет (как полуоткрытое соединение),
+// следующие отдают один апдейт.
+func fakeTelegram(t *testing.T) (*tgbotapi.BotAPI, *atomic.Int32) {
+	t.Helper()
+	var polls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`)
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			if polls.Add(1) == 1 {
+				<-release
+				return
+			}
+			fmt.Fprint(w, `{"ok":true,"result":[{"update_id":7,"message":{"message_id":1,"chat":{"id":42}}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+
+	api, err := tgbotapi.NewBotAPIWithClient("TOKEN", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api, &polls
+}
+
+func TestGetUpdatesStall(t *testing.T) {
+	oldTimeout, oldStall := pollTimeout, pollStallAfter
+	pollTimeout, pollStallAfter = 0, 100*time.Millisecond
+	defer func() { pollTimeout, pollStallAfter = oldTimeout, oldStall }()
+
+	api, polls := fakeTelegram(t)
+	b := &Bot{bot: api}
+
+	// зависший запрос бросаем по pollStallAfter, а не ждём вечно
+	start := time.Now()
+	if _, err := b.getUpdates(context.Background(), 0); err == nil {
+		t.Fatal("getUpdates on a hung connection: want error")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("stall detected after %s", d)
+	}
+
+	// следующий запрос идёт по новому соединению и получает апдейт
+	updates, err := b.getUpdates(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("getUpdates after reconnect: %v", err)
+	}
+	if len(updates) != 1 || updates[0].UpdateID != 7 {
+		t.Fatalf("updates = %+v, want one update 7", updates)
+	}
+	if n := polls.Load(); n != 2 {
+		t.Fatalf("getUpdates calls = %d, want 2", n)
+	}
+}
+
+func TestGetUpdatesCancel(t *testing.T) {
+	api, _ := fakeTelegram(t)
+	b := &Bot{bot: api}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	// по SIGTERM не ждём окончания long poll
+	if _, err := b.getUpdates(ctx, 0); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+// sentRequest — что бот отправил в recordingTelegram. Document — file_id при пересылке, пусто при загрузке файла.
+type sentRequest struct {
+	Method   string
+	Text     string
+	Document string
+}
+
+// recordingTelegram — сервер вместо api.telegram.org: запоминает sendMessage/sendDocument,
+// загруженным документам выдаёт file_id doc-1, doc-2, ...
+func recordingTelegram(t *testing.T) (*tgbotapi.BotAPI, func() []sentRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []sentRequest
+		docs int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		switch method {
+		case "getMe":
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`)
+		case "sendMessage", "sendDocument":
+			req := sentRequest{Method: method, Text: r.FormValue("text"), Document: r.FormValue("document")}
+			mu.Lock()
+			reqs = append(reqs, req)
+			fileID := req.Document
+			if method == "sendDocument" && fileID == "" {
+				docs++
+				fileID = fmt.Sprintf("doc-%d", docs)
+			}
+			mu.Unlock()
+			fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":1},"document":{"file_id":%q}}}`, fileID)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	api, err := tgbotapi.NewBotAPIWithClient("TOKEN", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api, func() []sentRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]sentRequest(nil), reqs...)
+	}
+}
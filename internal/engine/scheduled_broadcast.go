@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+// RecoverScheduledBroadcasts вызывается при старте: рассылки, прерванные посреди отправки, помечаем failed.
+func (b *Bot) RecoverScheduledBroadcasts() {
+	n, err := storage.FailInterruptedScheduledBroadcasts(b.db, b.profile.Name)
+	if err != nil {
+		b.logf("FailInterruptedScheduledBroadcasts error: %v", err)
+		return
+	}
+	if n > 0 {
+		b.logf("%d scheduled broadcasts were interrupted and marked failed", n)
+	}
+}
+
+// RunDueScheduledBroadcasts отправляет все рассылки, время которых наступило.
+func (b *Bot) RunDueScheduledBroadcasts(ctx context.Context) {
+	due, err := storage.ListDueScheduledBroadcasts(b.db, b.profile.Name, time.Now())
+	if err != nil {
+		b.logf("ListDueScheduledBroadcasts error: %v", err)
+		return
+	}
+
+	for _, sb := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		claimed, err := storage.ClaimScheduledBroadcast(b.db, sb.ID)
+		if err != nil {
+			b.logf("ClaimScheduledBroadcast error: %v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		a, err := storage.ParseAudience(sb.Audience)
+		if err != nil {
+			b.logf("scheduled broadcast #%d: %v", sb.ID, err)
+			if err := storage.FinishScheduledBroadcast(b.db, sb.ID, storage.SchedStatusFailed, 0); err != nil {
+				b.logf("FinishScheduledBroadcast error: %v", err)
+			}
+			if b.profile.NavigatorChatID != 0 {
+				_, _ = b.send(tgbotapi.NewMessage(b.profile.NavigatorChatID,
+					fmt.Sprintf("⚠️ Запланированная рассылка #%d не отправлена: не удалось разобрать аудиторию.", sb.ID)))
+			}
+			continue
+		}
+
+		payload := &BroadcastPayload{
+			Text:           sb.Text,
+			DocumentFileID: sb.DocumentFileID,
+			PhotoFileID:    sb.PhotoFileID,
+			SourceChatID:   sb.SourceChatID,
+			MessageIDs:     sb.MessageIDs,
+			Kind:           sb.Kind,
+		}
+		res := b.broadcastToAudience(ctx, payload, a, broadcastSource{CreatedBy: sb.CreatedBy, ScheduledID: sb.ID})
+
+		status := storage.SchedStatusSent
+		if ctx.Err() != nil {
+			// прервали на остановке — не выдаём за успешную
+			status = storage.SchedStatusFailed
+		}
+		if err := storage.FinishScheduledBroadcast(b.db, sb.ID, status, res.Sent); err != nil {
+			b.logf("FinishScheduledBroadcast error: %v", err)
+		}
+		b.logf("scheduled broadcast #%d %s, sent to %d users, failed %d", sb.ID, status, res.Sent, res.Failed)
+
+		if b.profile.NavigatorChatID != 0 {
+			text := broadcastDoneText(fmt.Sprintf("⏰ Запланированная рассылка #%d", sb.ID), res)
+			if status == storage.SchedStatusFailed {
+				text = fmt.Sprintf("⚠️ Запланированная рассылка #%d прервана остановкой бота, успели отправить %d пользователям.", sb.ID, res.Sent)
+			}
+			_, _ = b.send(tgbotapi.NewMessage(b.profile.NavigatorChatID, text))
+		}
+	}
+}
+
+func (b *Bot) sendScheduledList(chatID int64) {
+	list, err := storage.ListPendingScheduledBroadcasts(b.db, b.profile.Name)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(chatID, "Не удалось получить список рассылок (ошибка БД)."))
+		return
+	}
+	if len(list) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "Запланированных рассылок нет.")
+		msg.ReplyMarkup = b.navigatorMainKeyboard()
+		_, _ = b.send(msg)
+		return
+	}
+
+	loc := moscowLocation()
+	lines := []string{"🗓 Запланированные рассылки:"}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sb := range list {
+		lines = append(lines, fmt.Sprintf("#%d — %s — %s — %s", sb.ID, sb.RunAt.In(loc).Format("02.01.2006 15:04"),
+			audienceTitleSpec(sb.Audience), scheduledSummary(sb)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("❌ Отменить #%d", sb.ID), fmt.Sprintf("sched_cancel:%d", sb.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.send(msg)
+}
+
+func (b *Bot) handleScheduledCancelCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.From == nil || !b.cfg.ResponderIDs[int64(cq.From.ID)] {
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(cq.Data, "sched_cancel:"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	ok, err := storage.CancelScheduledBroadcast(b.db, b.profile.Name, id)
+	if err != nil {
+		_, _ = b.send(tgbotapi.NewMessage(cq.Message.Chat.ID, "Не удалось отменить рассылку (ошибка БД)."))
+		return
+	}
+	if !ok {
+		_, _ = b.send(tgbotapi.NewMessage(cq.Message.Chat.ID, fmt.Sprintf("Рассылка #%d уже отправлена или отменена.", id)))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(cq.Message.Chat.ID, fmt.Sprintf("Рассылка #%d отменена.", id))
+	msg.ReplyMarkup = b.navigatorMainKeyboard()
+	_, _ = b.send(msg)
+}
+
+func scheduledSummary(sb storage.ScheduledBroadcast) string {
+	var kind []string
+	switch {
+	case sb.Kind == "album":
+		kind = append(kind, fmt.Sprintf("[альбом, %d шт.]", len(sb.MessageIDs)))
+	case sb.Kind != "" && sb.Kind != "text":
+		kind = append(kind, templateKindLabel(sb.Kind))
+	case sb.Kind == "":
+		if sb.DocumentFileID != "" {
+			kind = append(kind, "[документ]")
+		}
+		if sb.PhotoFileID != "" {
+			kind = append(kind, "[фото]")
+		}
+	}
+
+	text := strings.TrimSpace(sb.Text)
+	r := []rune(text)
+	if len(r) > 50 {
+		text = string(r[:50]) + "…"
+	}
+	if text != "" {
+		kind = append(kind, text)
+	}
+	return strings.Join(kind, " ")
+}
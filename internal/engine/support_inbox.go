@@ -1,51 +1,51 @@
-package tg
+package engine
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 	"strings"
 	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
-	"TGBOT2/internal/config"
 	"TGBOT2/internal/storage"
 )
 
-func HandleSupportReply(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, m *tgbotapi.Message) {
+func (b *Bot) HandleSupportReply(ctx context.Context, m *tgbotapi.Message) {
 	if m == nil || m.Chat == nil || m.From == nil {
 		return
 	}
+	// процесс останавливается — не начинаем пересылку, которую можем не довести до конца
+	if ctx.Err() != nil {
+		return
+	}
 
-	isAccounting :=
-		m.Chat.ID == cfg.Accounting1ChatID ||
-			m.Chat.ID == cfg.Accounting2ChatID ||
-			m.Chat.ID == cfg.Accounting3ChatID ||
-			m.Chat.ID == cfg.Accounting4ChatID
-
-	isNavigator := m.Chat.ID == cfg.Bot1NavigatorChatID
+	isAccounting := b.profile.isAccountingChat(m.Chat.ID)
+	isNavigator := b.profile.NavigatorChatID != 0 && m.Chat.ID == b.profile.NavigatorChatID
 
 	if !isAccounting && !isNavigator {
 		return
 	}
+	// только reply
 	if m.ReplyToMessage == nil {
 		return
 	}
-	if !cfg.ResponderIDs[int64(m.From.ID)] {
+	// только разрешённые отвечающие
+	if !b.cfg.ResponderIDs[int64(m.From.ID)] {
 		return
 	}
 
-	target, ok, err := storage.GetReplyTarget(db, m.Chat.ID, m.ReplyToMessage.MessageID)
+	// ищем, кому надо ответить
+	target, ok, err := storage.GetReplyTarget(b.db, m.Chat.ID, m.ReplyToMessage.MessageID)
 	if err != nil {
-		log.Printf("GetReplyTarget error: %v", err)
+		b.logf("GetReplyTarget error: %v", err)
 		return
 	}
 	if !ok || target == nil {
 		return
 	}
 
-	alias := ResponderAlias(cfg, m.From)
+	alias := ResponderAlias(b.cfg, m.From)
 	a := strings.TrimSpace(strings.TrimSuffix(alias, ":")) // для текста пользователю (без двоеточия)
 
 	// ===== TEXT =====
@@ -53,19 +53,20 @@ func HandleSupportReply(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, m
 	if txt != "" && m.Document == nil && len(m.Photo) == 0 {
 		out := tgbotapi.NewMessage(target.UserChatID, fmt.Sprintf("%s:\n%s", a, txt))
 
-		// если ответили на файл/фото пользователя — делаем reply у пользователя
-		if m.ReplyToMessage != nil && (m.ReplyToMessage.Document != nil || len(m.ReplyToMessage.Photo) > 0) {
+		// ✅ reply ставим для support-сообщений и если ответили на файл/фото пользователя
+		if b.isSupportQuestion(target.UserChatID, target.UserMessageID) ||
+			m.ReplyToMessage.Document != nil || len(m.ReplyToMessage.Photo) > 0 {
 			out.ReplyToMessageID = target.UserMessageID
 		}
 
-		if _, err := bot.Send(out); err != nil {
-			log.Printf("send text to user error: %v", err)
+		if _, err := b.send(out); err != nil {
+			b.logf("send text to user error: %v", err)
 			return
 		}
 
 		// уведомление в бухгалтерию (текстом)
 		if isNavigator {
-			notifyAccountingNavigatorRepliedText(bot, db, cfg, target, a, txt)
+			b.notifyAccountingNavigatorRepliedText(target, a, txt)
 		}
 		return
 	}
@@ -82,14 +83,14 @@ func HandleSupportReply(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, m
 		}
 
 		doc.ReplyToMessageID = target.UserMessageID
-		if _, err := bot.Send(doc); err != nil {
-			log.Printf("send document to user error: %v", err)
+		if _, err := b.send(doc); err != nil {
+			b.logf("send document to user error: %v", err)
 			return
 		}
 
 		// ✅ уведомление в бухгалтерию: сам файл + подпись (reply на сообщение пользователя в группе)
 		if isNavigator {
-			notifyAccountingNavigatorRepliedMedia(bot, db, cfg, target, a, "document", m.Document.FileID, capText)
+			b.notifyAccountingNavigatorRepliedMedia(target, a, "document", m.Document.FileID, capText)
 		}
 		return
 	}
@@ -107,14 +108,14 @@ func HandleSupportReply(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, m
 		}
 
 		p.ReplyToMessageID = target.UserMessageID
-		if _, err := bot.Send(p); err != nil {
-			log.Printf("send photo to user error: %v", err)
+		if _, err := b.send(p); err != nil {
+			b.logf("send photo to user error: %v", err)
 			return
 		}
 
 		// ✅ уведомление в бухгалтерию: само фото + подпись (reply на сообщение пользователя в группе)
 		if isNavigator {
-			notifyAccountingNavigatorRepliedMedia(bot, db, cfg, target, a, "photo", ph.FileID, capText)
+			b.notifyAccountingNavigatorRepliedMedia(target, a, "photo", ph.FileID, capText)
 		}
 		return
 	}
@@ -122,15 +123,8 @@ func HandleSupportReply(bot *tgbotapi.BotAPI, db *sql.DB, cfg *config.Config, m
 
 // ---- helpers for notify ----
 
-func notifyAccountingNavigatorRepliedText(
-	bot *tgbotapi.BotAPI,
-	db *sql.DB,
-	cfg *config.Config,
-	target *storage.ReplyTarget,
-	navAlias string,
-	content string,
-) {
-	accChatID, groupMsgID, ok := findAccountingReplyAnchor(db, cfg, target)
+func (b *Bot) notifyAccountingNavigatorRepliedText(target *storage.ReplyTarget, navAlias string, content string) {
+	accChatID, groupMsgID, ok := b.findAccountingReplyAnchor(target)
 	if !ok {
 		return
 	}
@@ -148,13 +142,10 @@ func notifyAccountingNavigatorRepliedText(
 	text := fmt.Sprintf("‼️%s ответил пользователю:\n%s", navAlias, summary)
 	msg := tgbotapi.NewMessage(accChatID, text)
 	msg.ReplyToMessageID = groupMsgID
-	_, _ = bot.Send(msg)
+	_, _ = b.send(msg)
 }
 
-func notifyAccountingNavigatorRepliedMedia(
-	bot *tgbotapi.BotAPI,
-	db *sql.DB,
-	cfg *config.Config,
+func (b *Bot) notifyAccountingNavigatorRepliedMedia(
 	target *storage.ReplyTarget,
 	navAlias string,
 	kind string, // "document" | "photo"
@@ -163,11 +154,11 @@ func notifyAccountingNavigatorRepliedMedia(
 ) {
 	if strings.TrimSpace(fileID) == "" {
 		// если почему-то нет file_id — fallback на текстовое уведомление
-		notifyAccountingNavigatorRepliedText(bot, db, cfg, target, navAlias, captionText)
+		b.notifyAccountingNavigatorRepliedText(target, navAlias, captionText)
 		return
 	}
 
-	accChatID, groupMsgID, ok := findAccountingReplyAnchor(db, cfg, target)
+	accChatID, groupMsgID, ok := b.findAccountingReplyAnchor(target)
 	if !ok {
 		return
 	}
@@ -181,40 +172,38 @@ func notifyAccountingNavigatorRepliedMedia(
 			doc.Caption = cap
 		}
 		doc.ReplyToMessageID = groupMsgID
-		_, _ = bot.Send(doc)
+		_, _ = b.send(doc)
 	case "photo":
 		ph := tgbotapi.NewPhoto(accChatID, tgbotapi.FileID(fileID))
 		if cap != "" {
 			ph.Caption = cap
 		}
 		ph.ReplyToMessageID = groupMsgID
-		_, _ = bot.Send(ph)
+		_, _ = b.send(ph)
 	default:
 		// fallback
-		notifyAccountingNavigatorRepliedText(bot, db, cfg, target, navAlias, captionText)
+		b.notifyAccountingNavigatorRepliedText(target, navAlias, captionText)
 	}
 }
 
-func findAccountingReplyAnchor(db *sql.DB, cfg *config.Config, target *storage.ReplyTarget) (accChatID int64, groupMsgID int, ok bool) {
-	accChats := []int64{
-		cfg.Accounting1ChatID,
-		cfg.Accounting2ChatID,
-		cfg.Accounting3ChatID,
-		cfg.Accounting4ChatID,
+// findAccountingReplyAnchor: в какую бухгалтерскую группу профиля ушло сообщение пользователя и под каким id.
+func (b *Bot) findAccountingReplyAnchor(target *storage.ReplyTarget) (accChatID int64, groupMsgID int, ok bool) {
+	if len(b.profile.AccountingChatIDs) == 0 {
+		return 0, 0, false
 	}
 
-	accChatID, ok2, err := storage.FindMappedChatForUserMessage(db, target.UserChatID, target.UserMessageID, accChats)
+	accChatID, ok2, err := storage.FindMappedChatForUserMessage(b.db, target.UserChatID, target.UserMessageID, b.profile.AccountingChatIDs)
 	if err != nil {
-		log.Printf("notifyAccounting: FindMappedChatForUserMessage error: %v", err)
+		b.logf("notifyAccounting: FindMappedChatForUserMessage error: %v", err)
 		return 0, 0, false
 	}
 	if !ok2 || accChatID == 0 {
 		return 0, 0, false
 	}
 
-	groupMsgID, ok3, err := storage.GetMappedGroupMessageID(db, accChatID, target.UserChatID, target.UserMessageID)
+	groupMsgID, ok3, err := storage.GetMappedGroupMessageID(b.db, accChatID, target.UserChatID, target.UserMessageID)
 	if err != nil {
-		log.Printf("notifyAccounting: GetMappedGroupMessageID error: %v", err)
+		b.logf("notifyAccounting: GetMappedGroupMessageID error: %v", err)
 		return 0, 0, false
 	}
 	if !ok3 || groupMsgID == 0 {
@@ -225,7 +214,7 @@ func findAccountingReplyAnchor(db *sql.DB, cfg *config.Config, target *storage.R
 }
 
 func buildMediaCaption(navAlias string, captionText string, kind string) string {
-	alias := strings.TrimSpace(navAlias)
+	alias := strings.TrimSpace(strings.TrimSuffix(navAlias, ":"))
 	ct := strings.TrimSpace(captionText)
 
 	summary := ct
@@ -248,8 +237,5 @@ func buildMediaCaption(navAlias string, captionText string, kind string) string
 		return out
 	}
 	r := []rune(out)
-	if len(r) <= max {
-		return out
-	}
 	return string(r[:max-1]) + "…"
 }
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/storage"
+)
+
+func (b *Bot) HandleUserMessage(ctx context.Context, m *tgbotapi.Message) {
+	if m == nil || m.Chat == nil || m.From == nil {
+		return
+	}
+	if !m.Chat.IsPrivate() {
+		return
+	}
+
+	// Если бухгалтер/навигатор пишет боту в ЛИЧКУ — игнорируем
+	if b.cfg.ResponderIDs[int64(m.From.ID)] {
+		if m.IsCommand() && m.Command() == "start" {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, StartText()))
+		}
+		return
+	}
+
+	// ✅ пользователь пишет этому боту — снимаем отметку «недоступен» (если была)
+	u := mkUser(m)
+	u.Bot = b.profile.Name
+	_ = storage.UpsertUser(b.db, u)
+
+	if b.profile.BlockUnblock {
+		blocked, err := storage.IsUserBlockedByTelegramID(b.db, int64(m.From.ID))
+		if err != nil || blocked {
+			return
+		}
+	}
+
+	allowed, err := storage.IsUserAllowedByTelegramID(b.db, int64(m.From.ID))
+	if err != nil {
+		b.logf("IsUserAllowedByTelegramID error: %v", err)
+		return
+	}
+
+	// /start
+	if m.IsCommand() && m.Command() == "start" {
+		if !allowed {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, StartText()))
+			return
+		}
+
+		if b.profile.Applications {
+			// ✅ есть сохранённый черновик — предлагаем продолжить
+			st := b.getOrCreateState(int64(m.From.ID))
+			if st.Stage != stageIdle {
+				b.offerResume(m.Chat.ID, st)
+				b.saveState(int64(m.From.ID), st)
+				return
+			}
+
+			msg := tgbotapi.NewMessage(m.Chat.ID, StartText())
+			msg.ReplyMarkup = mainMenuKeyboard()
+			_, _ = b.send(msg)
+			return
+		}
+
+		name := strings.TrimSpace(m.From.FirstName)
+		if name == "" && strings.TrimSpace(m.From.UserName) != "" {
+			name = "@" + strings.TrimSpace(m.From.UserName)
+		}
+		if name != "" {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID,
+				fmt.Sprintf("Привет, %s!\nУ меня уже есть вся необходимая информация для нашего общения.", name),
+			))
+		} else {
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID,
+				"Привет!\nУ меня уже есть вся необходимая информация для нашего общения.",
+			))
+		}
+		if b.profile.CompanyPicker {
+			b.sendCompanyPicker(m.Chat.ID)
+		}
+		return
+	}
+
+	// Если не авторизован — ждём пароль МОЛЧА
+	if !allowed {
+		txt := strings.TrimSpace(m.Text)
+		if txt != "" && b.cfg.AccessPassword != "" && txt == b.cfg.AccessPassword {
+			_ = storage.SetUserAllowedByTelegramID(b.db, int64(m.From.ID), true)
+			msg := tgbotapi.NewMessage(m.Chat.ID, "Принято, можете писать нашей команде")
+			if b.profile.Applications {
+				msg.ReplyMarkup = mainMenuKeyboard()
+			}
+			_, _ = b.send(msg)
+			if b.profile.CompanyPicker {
+				b.sendCompanyPicker(m.Chat.ID)
+			}
+		}
+		return
+	}
+
+	if b.profile.Applications {
+		b.handleApplicationMessage(ctx, m)
+		return
+	}
+	b.routeUserMessage(m)
+}
+
+// routeUserMessage: обычное сообщение авторизованного пользователя —
+// в бухгалтерию (по компании или во все группы профиля) + навигатору.
+func (b *Bot) routeUserMessage(m *tgbotapi.Message) {
+	userHeader := "От: " + UserRef(m.From)
+	navHeader := userHeader
+
+	if b.profile.CompanyPicker {
+		// обработка выбора компании
+		if company, ok := TryParseCompanyChoice(m.Text); ok {
+			b.saveCompanyChoice(m.Chat.ID, int64(m.From.ID), company)
+			return
+		}
+
+		// ✅ пробуем взять из БД
+		company, err := storage.GetUserCompanyByTelegramID(b.db, int64(m.From.ID))
+		if err != nil {
+			b.logf("GetUserCompanyByTelegramID error: %v", err)
+			company = 0
+		}
+		if company == 0 {
+			b.sendCompanyPicker(m.Chat.ID)
+			return
+		}
+
+		accChatID := b.accountingChatIDByCompany(company)
+		if accChatID == 0 {
+			// тут отдельное сообщение, чтобы было видно, что проблема в env/chat_id, а не в выборе компании
+			_, _ = b.send(tgbotapi.NewMessage(m.Chat.ID, "Ошибка: не настроен chat_id бухгалтерии для выбранной компании."))
+			b.sendCompanyPicker(m.Chat.ID)
+			return
+		}
+
+		b.sendHeaderAndMap(accChatID, userHeader, m.Chat.ID, m.MessageID)
+		b.forwardAndMap(accChatID, m.Chat.ID, m.MessageID, m.Chat.ID, m.MessageID)
+
+		navHeader = fmt.Sprintf("%s\nКому: %s", userHeader, CompanyName(company))
+	} else {
+		// без выбора компании — во все бухгалтерские группы профиля
+		for _, accChatID := range b.profile.AccountingChatIDs {
+			if accChatID == 0 {
+				continue
+			}
+			b.sendHeaderAndMap(accChatID, userHeader, m.Chat.ID, m.MessageID)
+			b.forwardAndMap(accChatID, m.Chat.ID, m.MessageID, m.Chat.ID, m.MessageID)
+		}
+	}
+
+	// навигатору
+	b.sendHeaderAndMap(b.profile.NavigatorChatID, navHeader, m.Chat.ID, m.MessageID)
+	b.forwardAndMap(b.profile.NavigatorChatID, m.Chat.ID, m.MessageID, m.Chat.ID, m.MessageID)
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/config"
+)
+
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookPath: путь, на который телеграм шлёт апдейты этого бота ("/tg/bot3").
+func (b *Bot) WebhookPath() string {
+	return b.cfg.WebhookPath + "/" + b.profile.Name
+}
+
+// предел тела запроса: апдейт телеграма заметно меньше
+const webhookMaxBody = 1 << 20
+
+// updateDispatcher — куда webhookHandler кладёт апдейты (dispatcher; в тестах — заглушка).
+type updateDispatcher interface {
+	Dispatch(upd tgbotapi.Update)
+}
+
+// webhookHandler принимает Update JSON и отдаёт его в тот же dispatcher, что и polling.
+// Отвечает 200 сразу после постановки в очередь. Без secret token не принимает ничего:
+// иначе любой, кто узнал URL, мог бы подсовывать апдейты.
+func (b *Bot) webhookHandler(d updateDispatcher) http.Handler {
+	secret := []byte(b.cfg.WebhookSecretToken)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if len(secret) == 0 || subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), secret) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var upd tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBody)).Decode(&upd); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "update too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "bad update", http.StatusBadRequest)
+			return
+		}
+
+		d.Dispatch(upd)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// RegisterWebhook сообщает телеграму URL и secret_token. Без WebhookPublicURL ничего не делает.
+func (b *Bot) RegisterWebhook() error {
+	if b.cfg.WebhookPublicURL == "" {
+		b.logf("webhook: WEBHOOK_PUBLIC_URL is empty, serving %s without registration", b.WebhookPath())
+		return nil
+	}
+
+	params := tgbotapi.Params{}
+	params["url"] = b.cfg.WebhookPublicURL + b.WebhookPath()
+	params.AddNonEmpty("secret_token", b.cfg.WebhookSecretToken)
+
+	if _, err := b.bot.MakeRequest("setWebhook", params); err != nil {
+		return err
+	}
+	b.logf("webhook registered: %s", params["url"])
+	return nil
+}
+
+// ServeWebhooks поднимает один HTTP-сервер на все боты процесса и держит его до отмены ctx.
+// На остановке новые запросы не принимаются, начатые дорабатывают в пределах ShutdownTimeout.
+// Ошибка — порт не открылся или сервер упал: апдейты больше не приходят, процесс должен завершиться.
+func ServeWebhooks(ctx context.Context, cfg *config.Config, bots []*Bot) error {
+	if cfg.WebhookSecretToken == "" {
+		return errors.New("webhook: WEBHOOK_SECRET_TOKEN is empty")
+	}
+
+	// ✅ сначала порт: занят — узнаём до setWebhook, а не когда телеграм уже шлёт апдейты в никуда
+	ln, err := net.Listen("tcp", cfg.WebhookListenAddr)
+	if err != nil {
+		return fmt.Errorf("webhook: listen %s: %w", cfg.WebhookListenAddr, err)
+	}
+
+	mux := http.NewServeMux()
+	for _, b := range bots {
+		hctx, cancel := b.handlerContext(ctx)
+		defer cancel()
+		defer b.bg.Wait()
+
+		d := newDispatcher(hctx, b, b.cfg.UpdateWorkers)
+		defer d.Wait()
+
+		mux.Handle(b.WebhookPath(), b.webhookHandler(d))
+		if err := b.RegisterWebhook(); err != nil {
+			b.logf("webhook: setWebhook error: %v", err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.WebhookListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	log.Printf("webhook: listening on %s", ln.Addr())
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("webhook: serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	return nil
+}
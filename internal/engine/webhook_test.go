@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"TGBOT2/internal/config"
+)
+
+// recordDispatcher запоминает апдейты вместо обработки.
+type recordDispatcher struct {
+	got []tgbotapi.Update
+}
+
+func (r *recordDispatcher) Dispatch(upd tgbotapi.Update) { r.got = append(r.got, upd) }
+
+func TestWebhookHandler(t *testing.T) {
+	const secret = "s3cret"
+	update := `{"update_id":42,"message":{"message_id":7,"date":1760000000,"chat":{"id":100,"type":"private"},"from":{"id":100,"is_bot":false,"first_name":"Тест"},"text":"/start"}}`
+
+	tests := []struct {
+		name       string
+		secret     string // WEBHOOK_SECRET_TOKEN бота
+		method     string
+		header     string // X-Telegram-Bot-Api-Secret-Token
+		body       string
+		wantStatus int
+		wantUpdate bool
+	}{
+		{name: "GET", secret: secret, method: http.MethodGet, header: secret, wantStatus: http.StatusMethodNotAllowed},
+		{name: "wrong secret", secret: secret, method: http.MethodPost, header: "nope", body: update, wantStatus: http.StatusForbidden},
+		{name: "missing secret header", secret: secret, method: http.MethodPost, body: update, wantStatus: http.StatusForbidden},
+		{name: "bot without secret accepts nothing", method: http.MethodPost, body: update, wantStatus: http.StatusForbidden},
+		{name: "valid update", secret: secret, method: http.MethodPost, header: secret, body: update, wantStatus: http.StatusOK, wantUpdate: true},
+		{name: "broken JSON", secret: secret, method: http.MethodPost, header: secret, body: `{"update_id":`, wantStatus: http.StatusBadRequest},
+		{
+			name: "oversized body", secret: secret, method: http.MethodPost, header: secret,
+			body:       `{"update_id":1,"message":{"text":"` + strings.Repeat("я", webhookMaxBody) + `"}}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bot{cfg: &config.Config{WebhookSecretToken: tt.secret}}
+			d := &recordDispatcher{}
+
+			req := httptest.NewRequest(tt.method, "/tg/bot3", strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set(webhookSecretHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			b.webhookHandler(d).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !tt.wantUpdate {
+				if len(d.got) != 0 {
+					t.Errorf("dispatched %d updates, want none", len(d.got))
+				}
+				return
+			}
+			if len(d.got) != 1 {
+				t.Fatalf("dispatched %d updates, want 1", len(d.got))
+			}
+			upd := d.got[0]
+			if upd.UpdateID != 42 || upd.Message == nil || upd.Message.Text != "/start" || upd.Message.Chat.ID != 100 {
+				t.Errorf("dispatched %+v", upd)
+			}
+		})
+	}
+}
+
+func TestServeWebhooksFailsLoudly(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	cfg := &config.Config{WebhookListenAddr: busy.Addr().String(), WebhookSecretToken: "s3cret"}
+	if err := ServeWebhooks(context.Background(), cfg, nil); err == nil {
+		t.Error("port in use: want error")
+	}
+
+	cfg = &config.Config{WebhookListenAddr: "127.0.0.1:0"}
+	if err := ServeWebhooks(context.Background(), cfg, nil); err == nil {
+		t.Error("empty secret: want error")
+	}
+}
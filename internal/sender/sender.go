@@ -0,0 +1,341 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Лимиты телеграма: ~30 сообщений в секунду на бота, ~1 в секунду в один чат, ~20 в минуту в группу.
+// Небольшой burst разрешаем, чтобы интерактивные ответы из 2-3 сообщений не тормозили.
+const (
+	globalRate  = 30.0
+	globalBurst = 30.0
+
+	privateRate  = 1.0
+	privateBurst = 3.0
+
+	groupRate  = 20.0 / 60.0
+	groupBurst = 5.0
+
+	maxAttempts = 5
+	maxBackoff  = 30 * time.Second
+
+	// сколько корзин по чатам держим до чистки простаивающих
+	maxChatBuckets = 10000
+)
+
+// PermanentError: телеграм отказал окончательно (400/403/404 ...) — повтор не поможет.
+// Code 0 — ответа телеграма нет (таймаут, обрыв, непонятный ответ): не повторяем, чтобы не задвоить.
+type PermanentError struct {
+	ChatID int64
+	Code   int
+	Err    error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("telegram chat %d: %d %v", e.ChatID, e.Code, e.Err)
+}
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent: ошибка окончательная (не сеть и не лимит).
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// IsForbidden: 403 — пользователь заблокировал бота, удалил аккаунт или бота выкинули из группы.
+func IsForbidden(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe) && pe.Code == 403
+}
+
+// Sender — единая точка исходящих сообщений бота: лимиты, retry_after, повторы сетевых ошибок.
+// Один Sender на один токен (лимиты телеграм считает на бота).
+type Sender struct {
+	api *tgbotapi.BotAPI
+
+	mu         sync.Mutex
+	global     bucket
+	chats      map[int64]*bucket
+	pauseUntil time.Time // после 429 телеграм просит подождать всех
+
+	// OnForbidden вызывается на 403 при отправке в личный чат (chatID > 0),
+	// чтобы вызывающий пометил пользователя недоступным. Задаётся до начала отправок.
+	OnForbidden func(chatID int64, err error)
+}
+
+func New(api *tgbotapi.BotAPI) *Sender {
+	return &Sender{
+		api:    api,
+		global: bucket{rate: globalRate, burst: globalBurst, tokens: globalBurst, last: time.Now()},
+		chats:  map[int64]*bucket{},
+	}
+}
+
+// Send отправляет сообщение с учётом лимитов. Возвращает *PermanentError, если телеграм отказал
+// окончательно, ctx.Err() при отмене, или последнюю ошибку после исчерпания повторов.
+func (s *Sender) Send(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	err := s.do(ctx, chatIDOf(c), func() error {
+		var err error
+		msg, err = s.api.Send(c)
+		return err
+	})
+	return msg, err
+}
+
+// Request — то же для методов без Message в ответе.
+func (s *Sender) Request(ctx context.Context, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(ctx, chatIDOf(c), func() error {
+		var err error
+		resp, err = s.api.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// MakeRequest — для методов, которых нет в конфигурациях библиотеки (copyMessages и т.п.).
+func (s *Sender) MakeRequest(ctx context.Context, chatID int64, endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(ctx, chatID, func() error {
+		var err error
+		resp, err = s.api.MakeRequest(endpoint, params)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Sender) do(ctx context.Context, chatID int64, call func() error) error {
+	backoff := time.Second
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := sleepCtx(ctx, s.reserve(chatID)); err != nil {
+			return err
+		}
+
+		err := call()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		switch class, apiErr := classify(err); class {
+		case errRateLimited:
+			// Too Many Requests: retry after N — ставим на паузу всю отправку
+			wait := time.Duration(apiErr.RetryAfter) * time.Second
+			if wait <= 0 {
+				wait = backoff
+			}
+			s.pause(wait)
+			log.Printf("sender: 429 for chat %d, retry after %s", chatID, wait)
+			continue
+		case errPermanent:
+			code := 0
+			if apiErr != nil {
+				code = apiErr.Code
+			}
+			pe := &PermanentError{ChatID: chatID, Code: code, Err: err}
+			if code == 403 && chatID > 0 && s.OnForbidden != nil {
+				s.OnForbidden(chatID, pe)
+			}
+			return pe
+		}
+
+		// соединение не установилось / 5xx: повтор с нарастающей паузой
+		if attempt == maxAttempts {
+			break
+		}
+		log.Printf("sender: chat %d attempt %d failed: %v; retry in %s", chatID, attempt, err, backoff)
+		if err := sleepCtx(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+
+	return fmt.Errorf("send to chat %d failed after %d attempts: %w", chatID, maxAttempts, lastErr)
+}
+
+type errClass int
+
+const (
+	errRetry       errClass = iota // запрос точно не выполнен — можно повторить
+	errRateLimited                 // 429: ждём retry_after и повторяем
+	errPermanent                   // повтор не поможет или может задвоить сообщение
+)
+
+// classify: повторяем только то, что телеграм заведомо не выполнил — 5xx, 429 и ошибки
+// установки соединения (dial, DNS, connection refused). Таймаут после отправки запроса,
+// оборванный ответ или непонятный JSON — сообщение могло уже уйти, повтор даст дубль.
+func classify(err error) (errClass, *tgbotapi.Error) {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == 429 || apiErr.RetryAfter > 0:
+			return errRateLimited, apiErr
+		case apiErr.Code >= 500:
+			return errRetry, apiErr
+		default:
+			return errPermanent, apiErr
+		}
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return errRetry, nil
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return errRetry, nil
+	}
+	return errPermanent, nil
+}
+
+// reserve занимает место в глобальной и чатовой корзинах и возвращает, сколько ждать.
+func (s *Sender) reserve(chatID int64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wait := s.global.reserve(now)
+
+	if chatID != 0 {
+		cb := s.chats[chatID]
+		if cb == nil {
+			if len(s.chats) >= maxChatBuckets {
+				s.pruneLocked(now)
+			}
+			cb = newChatBucket(chatID, now)
+			s.chats[chatID] = cb
+		}
+		if w := cb.reserve(now); w > wait {
+			wait = w
+		}
+	}
+
+	if p := s.pauseUntil.Sub(now); p > wait {
+		wait = p
+	}
+	return wait
+}
+
+func (s *Sender) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.pauseUntil) {
+		s.pauseUntil = until
+	}
+}
+
+// pruneLocked выкидывает корзины чатов, которые уже полностью восстановились.
+func (s *Sender) pruneLocked(now time.Time) {
+	for id, cb := range s.chats {
+		if cb.idle(now) {
+			delete(s.chats, id)
+		}
+	}
+}
+
+// bucket — token bucket с резервированием: токены могут уйти в минус,
+// тогда вызывающий ждёт, пока минус «отработается».
+type bucket struct {
+	rate   float64 // токенов в секунду
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newChatBucket(chatID int64, now time.Time) *bucket {
+	// отрицательный chat_id — группа/канал
+	if chatID < 0 {
+		return &bucket{rate: groupRate, burst: groupBurst, tokens: groupBurst, last: now}
+	}
+	return &bucket{rate: privateRate, burst: privateBurst, tokens: privateBurst, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *bucket) idle(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// chatIDOf: куда уходит сообщение (для лимита на чат). 0 — неизвестно, только общий лимит.
+func chatIDOf(c tgbotapi.Chattable) int64 {
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		return v.ChatID
+	case tgbotapi.ForwardConfig:
+		return v.ChatID
+	case tgbotapi.CopyMessageConfig:
+		return v.ChatID
+	case tgbotapi.PhotoConfig:
+		return v.ChatID
+	case tgbotapi.DocumentConfig:
+		return v.ChatID
+	case tgbotapi.AudioConfig:
+		return v.ChatID
+	case tgbotapi.VideoConfig:
+		return v.ChatID
+	case tgbotapi.VoiceConfig:
+		return v.ChatID
+	case tgbotapi.AnimationConfig:
+		return v.ChatID
+	case tgbotapi.VideoNoteConfig:
+		return v.ChatID
+	case tgbotapi.StickerConfig:
+		return v.ChatID
+	case tgbotapi.MediaGroupConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return v.ChatID
+	default:
+		return 0
+	}
+}
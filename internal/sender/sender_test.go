@@ -0,0 +1,136 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestClassify(t *testing.T) {
+	dial := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED},
+	}}
+	readTimeout := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: &net.OpError{
+		Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded,
+	}}
+
+	tests := []struct {
+		name string
+		err  error
+		want errClass
+	}{
+		{"400 bad request", &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}, errPermanent},
+		{"403 blocked", &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, errPermanent},
+		{"429", &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}, errRateLimited},
+		{"502", &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}, errRetry},
+		{"dial connection refused", dial, errRetry},
+		{"dns", &url.Error{Op: "Post", Err: &net.DNSError{Err: "no such host", Name: "api.telegram.org"}}, errRetry},
+		{"bare ECONNREFUSED", syscall.ECONNREFUSED, errRetry},
+		{"read timeout after request sent", readTimeout, errPermanent},
+		{"client timeout", &url.Error{Op: "Post", Err: context.DeadlineExceeded}, errPermanent},
+		{"truncated response", io.ErrUnexpectedEOF, errPermanent},
+		{"bad JSON", &json.SyntaxError{Offset: 1}, errPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := classify(tt.err); got != tt.want {
+				t.Errorf("classify = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	t.Run("ambiguous error is not retried", func(t *testing.T) {
+		s := New(nil)
+		calls := 0
+		err := s.do(context.Background(), 0, func() error {
+			calls++
+			return io.ErrUnexpectedEOF
+		})
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+		if !IsPermanent(err) {
+			t.Errorf("err = %v, want permanent", err)
+		}
+	})
+
+	t.Run("403 reports forbidden", func(t *testing.T) {
+		s := New(nil)
+		var forbidden int64
+		s.OnForbidden = func(chatID int64, _ error) { forbidden = chatID }
+		err := s.do(context.Background(), 100, func() error { return &tgbotapi.Error{Code: 403} })
+		if !IsForbidden(err) || forbidden != 100 {
+			t.Errorf("err = %v, OnForbidden chat = %d", err, forbidden)
+		}
+	})
+
+	t.Run("dial error is retried", func(t *testing.T) {
+		s := New(nil)
+		calls := 0
+		err := s.do(context.Background(), 0, func() error {
+			calls++
+			if calls == 1 {
+				return refused
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("err = %v, calls = %d; want nil, 2", err, calls)
+		}
+	})
+
+	t.Run("cancel stops retries", func(t *testing.T) {
+		s := New(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := s.do(ctx, 0, func() error {
+			calls++
+			cancel()
+			return refused
+		})
+		if !errors.Is(err, context.Canceled) || calls != 1 {
+			t.Errorf("err = %v, calls = %d", err, calls)
+		}
+	})
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := newChatBucket(100, now)
+
+	// личка: burst 3 без ожидания, дальше — 1 в секунду
+	for i := 0; i < 3; i++ {
+		if w := b.reserve(now); w != 0 {
+			t.Fatalf("reserve #%d waits %s, want 0", i+1, w)
+		}
+	}
+	if w := b.reserve(now); w != time.Second {
+		t.Errorf("4th reserve waits %s, want 1s", w)
+	}
+	if w := b.reserve(now); w != 2*time.Second {
+		t.Errorf("5th reserve waits %s, want 2s", w)
+	}
+
+	// за 5 секунд долг отработан, корзина снова полная — чат можно выкинуть из карты
+	if !b.idle(now.Add(5 * time.Second)) {
+		t.Error("bucket not idle after refill")
+	}
+
+	g := newChatBucket(-100, now)
+	if g.rate != groupRate || g.burst != groupBurst {
+		t.Errorf("group bucket: rate %v burst %v", g.rate, g.burst)
+	}
+}
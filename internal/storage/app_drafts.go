@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveAppDraft сохраняет состояние мастера заявки bot3 (JSON) для пользователя.
+func SaveAppDraft(db *sql.DB, telegramID int64, stateJSON string) error {
+	_, err := db.Exec(`
+INSERT INTO app_drafts (telegram_id, state_json, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT(telegram_id) DO UPDATE SET
+  state_json=excluded.state_json,
+  updated_at=excluded.updated_at;
+`, telegramID, stateJSON, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("save app draft: %w", err)
+	}
+	return nil
+}
+
+// GetAppDraft возвращает сохранённое состояние и время последнего изменения.
+func GetAppDraft(db *sql.DB, telegramID int64) (string, time.Time, bool, error) {
+	row := db.QueryRow(`SELECT state_json, updated_at FROM app_drafts WHERE telegram_id=? LIMIT 1`, telegramID)
+
+	var stateJSON string
+	var updatedAt int64
+	if err := row.Scan(&stateJSON, &updatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return "", time.Time{}, false, nil
+		}
+		return "", time.Time{}, false, err
+	}
+	return stateJSON, time.Unix(updatedAt, 0), true, nil
+}
+
+func DeleteAppDraft(db *sql.DB, telegramID int64) error {
+	_, err := db.Exec(`DELETE FROM app_drafts WHERE telegram_id=?`, telegramID)
+	return err
+}
+
+// PurgeAppDraftsOlderThan удаляет брошенные черновики, не менявшиеся с before.
+func PurgeAppDraftsOlderThan(db *sql.DB, before time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM app_drafts WHERE updated_at < ?`, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("purge app drafts: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// статусы заявки bot3
+const (
+	AppStatusPending  = "pending"   // ждёт решения в чате подтверждения
+	AppStatusAwaitFix = "await_fix" // нажали «Правка», ждём причину reply
+	AppStatusApproved = "approved"  // счёт отправлен пользователю
+	AppStatusRejected = "rejected"  // причина правок отправлена пользователю
+	// пользователь исправил отклонённую заявку и отправил заново (новая заявка ссылается через parent_id)
+	AppStatusResubmitted = "resubmitted"
+)
+
+type Application struct {
+	ID            int64
+	InvoiceNo     int64
+	InvoiceDate   int64 // unix, дата в счёте (нужна, чтобы пересобрать файл после рестарта)
+	UserChatID    int64
+	UserMessageID int
+	Text          string
+	DraftJSON     string
+	Status        string
+
+	ApprovalChatID    int64
+	ApprovalMessageID int
+
+	XlsxPath string
+	PdfPath  string
+	TempDir  string
+
+	RejectReason string
+	ParentID     int64 // исправление отклонённой заявки с этим id
+}
+
+const applicationColumns = `id, invoice_no, invoice_date, user_chat_id, user_message_id, text, draft_json, status,
+       approval_chat_id, approval_message_id, xlsx_path, pdf_path, temp_dir, reject_reason, parent_id`
+
+func CreateApplication(db *sql.DB, a *Application) (int64, error) {
+	res, err := db.Exec(`
+INSERT INTO applications (
+  invoice_no, invoice_date, user_chat_id, user_message_id, text, draft_json, status,
+  approval_chat_id, approval_message_id, xlsx_path, pdf_path, temp_dir, parent_id
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`, a.InvoiceNo, a.InvoiceDate, a.UserChatID, a.UserMessageID, a.Text, a.DraftJSON, a.Status,
+		a.ApprovalChatID, a.ApprovalMessageID, a.XlsxPath, a.PdfPath, a.TempDir, a.ParentID)
+	if err != nil {
+		return 0, fmt.Errorf("create application: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create application: %w", err)
+	}
+	a.ID = id
+	return id, nil
+}
+
+func GetApplicationByApprovalMessage(db *sql.DB, approvalChatID int64, approvalMessageID int) (*Application, bool, error) {
+	row := db.QueryRow(`
+SELECT `+applicationColumns+`
+FROM applications
+WHERE approval_chat_id=? AND approval_message_id=?
+LIMIT 1;
+`, approvalChatID, approvalMessageID)
+	return scanApplication(row)
+}
+
+func GetApplication(db *sql.DB, id int64) (*Application, bool, error) {
+	return scanApplication(db.QueryRow(`SELECT `+applicationColumns+` FROM applications WHERE id=?`, id))
+}
+
+func scanApplication(row *sql.Row) (*Application, bool, error) {
+	var a Application
+	err := row.Scan(
+		&a.ID, &a.InvoiceNo, &a.InvoiceDate, &a.UserChatID, &a.UserMessageID, &a.Text, &a.DraftJSON, &a.Status,
+		&a.ApprovalChatID, &a.ApprovalMessageID, &a.XlsxPath, &a.PdfPath, &a.TempDir, &a.RejectReason, &a.ParentID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &a, true, nil
+}
+
+// RejectApplication: await_fix -> rejected с причиной. false — заявку уже обработали.
+func RejectApplication(db *sql.DB, id int64, reason string) (bool, error) {
+	res, err := db.Exec(`
+UPDATE applications
+SET status=?, reject_reason=?, updated_at=CURRENT_TIMESTAMP
+WHERE id=? AND status=?;
+`, AppStatusRejected, reason, id, AppStatusAwaitFix)
+	if err != nil {
+		return false, fmt.Errorf("reject application: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetApplicationStatus переводит заявку в новый статус, только если она сейчас в одном из from.
+// Возвращает false, если заявку уже обработали (например, второй клик по кнопке).
+func SetApplicationStatus(db *sql.DB, id int64, status string, from ...string) (bool, error) {
+	q := `UPDATE applications SET status=?, updated_at=CURRENT_TIMESTAMP WHERE id=?`
+	args := []any{status, id}
+	if len(from) > 0 {
+		q += ` AND status IN (` + placeholders(len(from)) + `)`
+		for _, s := range from {
+			args = append(args, s)
+		}
+	}
+
+	res, err := db.Exec(q, args...)
+	if err != nil {
+		return false, fmt.Errorf("set application status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func SetApplicationFiles(db *sql.DB, id int64, xlsxPath, pdfPath, tempDir string) error {
+	_, err := db.Exec(`
+UPDATE applications
+SET xlsx_path=?, pdf_path=?, temp_dir=?, updated_at=CURRENT_TIMESTAMP
+WHERE id=?;
+`, xlsxPath, pdfPath, tempDir, id)
+	if err != nil {
+		return fmt.Errorf("set application files: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// виды аудитории рассылки
+const (
+	AudienceAll     = "all"     // все в базе (старое поведение, только для старых запланированных рассылок)
+	AudienceAllowed = "allowed" // allowed=1 и не в бане
+	AudienceCompany = "company" // + выбранная компания (users.company)
+	AudienceActive  = "active"  // + писали боту за последние N дней
+	AudienceList    = "list"    // явный список @username / telegram id
+)
+
+// Audience — кому уходит рассылка. Хранится строкой (см. String / ParseAudience).
+type Audience struct {
+	Kind    string
+	Company int      // для AudienceCompany
+	Days    int      // для AudienceActive
+	Refs    []string // для AudienceList: "@username" или "123456"
+}
+
+// String: "allowed", "company:2", "active:30", "list:@ivan,123456".
+func (a Audience) String() string {
+	switch a.Kind {
+	case AudienceCompany:
+		return fmt.Sprintf("%s:%d", AudienceCompany, a.Company)
+	case AudienceActive:
+		return fmt.Sprintf("%s:%d", AudienceActive, a.Days)
+	case AudienceList:
+		return AudienceList + ":" + strings.Join(a.Refs, ",")
+	case "":
+		return AudienceAll
+	default:
+		return a.Kind
+	}
+}
+
+// ParseAudience разбирает строку из String. Пустая строка — AudienceAll
+// (рассылки, запланированные до появления выбора аудитории).
+func ParseAudience(s string) (Audience, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Audience{Kind: AudienceAll}, nil
+	}
+
+	kind, arg, _ := strings.Cut(s, ":")
+	switch kind {
+	case AudienceAll, AudienceAllowed:
+		return Audience{Kind: kind}, nil
+	case AudienceCompany:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return Audience{}, fmt.Errorf("bad audience %q", s)
+		}
+		return Audience{Kind: kind, Company: n}, nil
+	case AudienceActive:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return Audience{}, fmt.Errorf("bad audience %q", s)
+		}
+		return Audience{Kind: kind, Days: n}, nil
+	case AudienceList:
+		refs, bad := ParseUserRefs(arg)
+		if len(refs) == 0 || len(bad) > 0 {
+			return Audience{}, fmt.Errorf("bad audience %q", s)
+		}
+		return Audience{Kind: kind, Refs: refs}, nil
+	default:
+		return Audience{}, fmt.Errorf("bad audience %q", s)
+	}
+}
+
+// ParseUserRefs: список @username / telegram id через пробелы, запятые или с новой строки.
+// Возвращает нормализованные ссылки (без повторов) и то, что не удалось разобрать.
+func ParseUserRefs(text string) (refs []string, bad []string) {
+	seen := map[string]bool{}
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t' || r == '\r'
+	})
+	for _, f := range fields {
+		var ref string
+		if strings.HasPrefix(f, "@") {
+			u := strings.ToLower(strings.TrimPrefix(f, "@"))
+			if u == "" {
+				bad = append(bad, f)
+				continue
+			}
+			ref = "@" + u
+		} else {
+			id, err := strconv.ParseInt(f, 10, 64)
+			if err != nil || id <= 0 {
+				bad = append(bad, f)
+				continue
+			}
+			ref = strconv.FormatInt(id, 10)
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, bad
+}
+
+// ListAudienceChatIDs — chat_id получателей рассылки бота. Недоступные боту пользователи
+// (см. MarkUserUnreachable) пропускаются всегда. missing — ссылки из списка, которые
+// не нашлись среди подходящих пользователей (нет в базе, не allowed или в бане).
+func ListAudienceChatIDs(db *sql.DB, bot string, a Audience, now time.Time) (chatIDs []int64, missing []string, err error) {
+	switch a.Kind {
+	case "", AudienceAll:
+		chatIDs, err = ListAllUserChatIDs(db, bot)
+	case AudienceAllowed:
+		chatIDs, err = ListAllowedNotBlockedUserChatIDs(db, bot)
+	case AudienceCompany:
+		chatIDs, err = queryChatIDs(db, `
+SELECT chat_id
+FROM users
+WHERE allowed = 1 AND blocked = 0 AND company = ? AND instr(unreachable_bots, ?) = 0
+`, a.Company, botMark(bot))
+	case AudienceActive:
+		since := now.Add(-time.Duration(a.Days) * 24 * time.Hour)
+		chatIDs, err = queryChatIDs(db, `
+SELECT chat_id
+FROM users
+WHERE allowed = 1 AND blocked = 0 AND last_seen_at >= ? AND instr(unreachable_bots, ?) = 0
+`, since.Unix(), botMark(bot))
+	case AudienceList:
+		chatIDs, missing, err = listChatIDsByRefs(db, bot, a.Refs)
+	default:
+		err = fmt.Errorf("unknown audience %q", a.Kind)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("list audience %s: %w", a.Kind, err)
+	}
+	return chatIDs, missing, nil
+}
+
+func listChatIDsByRefs(db *sql.DB, bot string, refs []string) ([]int64, []string, error) {
+	var out []int64
+	var missing []string
+	seen := map[int64]bool{}
+
+	for _, ref := range refs {
+		var row *sql.Row
+		if strings.HasPrefix(ref, "@") {
+			row = db.QueryRow(`
+SELECT chat_id
+FROM users
+WHERE lower(username) = ? AND allowed = 1 AND blocked = 0 AND instr(unreachable_bots, ?) = 0
+LIMIT 1
+`, strings.ToLower(strings.TrimPrefix(ref, "@")), botMark(bot))
+		} else {
+			id, err := strconv.ParseInt(ref, 10, 64)
+			if err != nil {
+				missing = append(missing, ref)
+				continue
+			}
+			row = db.QueryRow(`
+SELECT chat_id
+FROM users
+WHERE telegram_id = ? AND allowed = 1 AND blocked = 0 AND instr(unreachable_bots, ?) = 0
+LIMIT 1
+`, id, botMark(bot))
+		}
+
+		var chatID int64
+		if err := row.Scan(&chatID); err != nil {
+			if err == sql.ErrNoRows {
+				missing = append(missing, ref)
+				continue
+			}
+			return nil, nil, err
+		}
+		if !seen[chatID] {
+			seen[chatID] = true
+			out = append(out, chatID)
+		}
+	}
+	return out, missing, nil
+}
+
+func queryChatIDs(db *sql.DB, q string, args ...any) ([]int64, error) {
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, err
+		}
+		out = append(out, chatID)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Broadcast — одна фактическая отправка рассылки (сразу или по расписанию).
+type Broadcast struct {
+	ID          int64
+	Bot         string
+	ScheduledID int64 // 0 — отправлена сразу
+	RecurringID int64 // регулярная рассылка, которая её запустила
+
+	Text           string
+	DocumentFileID string
+	PhotoFileID    string
+	SourceChatID   int64
+	MessageIDs     []int
+	Kind           string
+	Audience       string // storage.Audience.String()
+
+	CreatedBy int64
+	Total     int
+	Sent      int
+	Failed    int
+
+	StartedAt  time.Time
+	FinishedAt time.Time // zero — ещё идёт (или процесс упал посреди)
+}
+
+// BroadcastDelivery — результат по одному получателю. Error пустой — доставлено.
+type BroadcastDelivery struct {
+	BroadcastID int64
+	ChatID      int64
+	MessageID   int
+	Error       string
+	SentAt      time.Time
+
+	Username string // для отчёта, из users
+}
+
+func CreateBroadcast(db *sql.DB, b *Broadcast) (int64, error) {
+	if b.StartedAt.IsZero() {
+		b.StartedAt = time.Now()
+	}
+	res, err := db.Exec(`
+INSERT INTO broadcasts (bot, scheduled_id, recurring_id, text, document_file_id, photo_file_id, source_chat_id, message_ids, kind, audience, created_by, total, started_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`, b.Bot, b.ScheduledID, b.RecurringID, b.Text, b.DocumentFileID, b.PhotoFileID, b.SourceChatID, joinInts(b.MessageIDs), b.Kind, b.Audience, b.CreatedBy, b.Total, b.StartedAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("create broadcast: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create broadcast: %w", err)
+	}
+	b.ID = id
+	return id, nil
+}
+
+func AddBroadcastDelivery(db *sql.DB, broadcastID, chatID int64, messageID int, errText string) error {
+	_, err := db.Exec(`
+INSERT INTO broadcast_deliveries (broadcast_id, chat_id, message_id, error, sent_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(broadcast_id, chat_id) DO UPDATE SET
+  message_id=excluded.message_id,
+  error=excluded.error,
+  sent_at=excluded.sent_at;
+`, broadcastID, chatID, messageID, errText, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("add broadcast delivery: %w", err)
+	}
+	return nil
+}
+
+func FinishBroadcast(db *sql.DB, id int64, sent, failed int) error {
+	_, err := db.Exec(`UPDATE broadcasts SET sent=?, failed=?, finished_at=? WHERE id=?`,
+		sent, failed, time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("finish broadcast: %w", err)
+	}
+	return nil
+}
+
+const broadcastColumns = `id, bot, scheduled_id, recurring_id, text, document_file_id, photo_file_id, source_chat_id, message_ids, kind, audience, created_by, total, sent, failed, started_at, finished_at`
+
+func scanBroadcast(sc interface{ Scan(...any) error }) (*Broadcast, error) {
+	var b Broadcast
+	var startedAt int64
+	var finishedAt sql.NullInt64
+	var messageIDs string
+	if err := sc.Scan(&b.ID, &b.Bot, &b.ScheduledID, &b.RecurringID, &b.Text, &b.DocumentFileID, &b.PhotoFileID, &b.SourceChatID, &messageIDs, &b.Kind, &b.Audience,
+		&b.CreatedBy, &b.Total, &b.Sent, &b.Failed, &startedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	b.StartedAt = time.Unix(startedAt, 0)
+	b.MessageIDs = splitInts(messageIDs)
+	if finishedAt.Valid {
+		b.FinishedAt = time.Unix(finishedAt.Int64, 0)
+	}
+	return &b, nil
+}
+
+func GetBroadcast(db *sql.DB, bot string, id int64) (*Broadcast, bool, error) {
+	row := db.QueryRow(`SELECT `+broadcastColumns+` FROM broadcasts WHERE id=? AND bot=?`, id, bot)
+	b, err := scanBroadcast(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// ListRecentBroadcasts — последние рассылки бота, новые сверху.
+func ListRecentBroadcasts(db *sql.DB, bot string, limit int) ([]Broadcast, error) {
+	rows, err := db.Query(`SELECT `+broadcastColumns+` FROM broadcasts WHERE bot=? ORDER BY id DESC LIMIT ?`, bot, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Broadcast
+	for rows.Next() {
+		b, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *b)
+	}
+	return out, rows.Err()
+}
+
+// ListFailedDeliveries — получатели, которым рассылка не дошла (с @username, если знаем).
+func ListFailedDeliveries(db *sql.DB, broadcastID int64) ([]BroadcastDelivery, error) {
+	rows, err := db.Query(`
+SELECT d.broadcast_id, d.chat_id, d.message_id, d.error, d.sent_at, COALESCE(u.username, '')
+FROM broadcast_deliveries d
+LEFT JOIN users u ON u.chat_id = d.chat_id
+WHERE d.broadcast_id=? AND d.error <> ''
+ORDER BY d.sent_at, d.chat_id;
+`, broadcastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BroadcastDelivery
+	for rows.Next() {
+		var d BroadcastDelivery
+		var sentAt int64
+		if err := rows.Scan(&d.BroadcastID, &d.ChatID, &d.MessageID, &d.Error, &sentAt, &d.Username); err != nil {
+			return nil, err
+		}
+		d.SentAt = time.Unix(sentAt, 0)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
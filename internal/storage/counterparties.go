@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Counterparty — кэш данных контрагента по ИНН (см. пакет companylookup).
+type Counterparty struct {
+	INN     string
+	KPP     string
+	OGRN    string
+	Name    string
+	Address string
+	Source  string // провайдер, откуда данные
+	// Manual — навигатор исправил запись руками: она не устаревает, пока её не обновят принудительно
+	Manual    bool
+	UpdatedBy int64 // кто исправил (telegram id)
+	FetchedAt time.Time
+}
+
+const counterpartyColumns = `inn, kpp, ogrn, name, address, source, manual, updated_by, fetched_at`
+
+func GetCounterparty(db *sql.DB, inn string) (*Counterparty, bool, error) {
+	row := db.QueryRow(`SELECT `+counterpartyColumns+` FROM counterparties WHERE inn=?`, inn)
+	c, err := scanCounterparty(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get counterparty: %w", err)
+	}
+	return c, true, nil
+}
+
+// SaveCounterparty — свежие данные от провайдера (снимает ручную правку).
+func SaveCounterparty(db *sql.DB, c *Counterparty) error {
+	_, err := db.Exec(`
+INSERT INTO counterparties (inn, kpp, ogrn, name, address, source, manual, updated_by, fetched_at)
+VALUES (?, ?, ?, ?, ?, ?, 0, 0, ?)
+ON CONFLICT(inn) DO UPDATE SET
+  kpp=excluded.kpp,
+  ogrn=excluded.ogrn,
+  name=excluded.name,
+  address=excluded.address,
+  source=excluded.source,
+  manual=0,
+  updated_by=0,
+  fetched_at=excluded.fetched_at;
+`, c.INN, c.KPP, c.OGRN, c.Name, c.Address, c.Source, c.FetchedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("save counterparty: %w", err)
+	}
+	return nil
+}
+
+// поля, которые навигатор может исправить руками
+const (
+	CounterpartyKPP     = "kpp"
+	CounterpartyName    = "name"
+	CounterpartyAddress = "address"
+)
+
+// SetCounterpartyField — ручная правка поля уже загруженной записи.
+// Записи нет — ничего не создаём (false): пустая ручная запись навсегда закрыла бы реестр для этого ИНН.
+func SetCounterpartyField(db *sql.DB, inn, field, value string, by int64) (bool, error) {
+	switch field {
+	case CounterpartyKPP, CounterpartyName, CounterpartyAddress:
+	default:
+		return false, fmt.Errorf("set counterparty field: unknown field %q", field)
+	}
+	res, err := db.Exec(`UPDATE counterparties SET `+field+`=?, manual=1, updated_by=? WHERE inn=?`, value, by, inn)
+	if err != nil {
+		return false, fmt.Errorf("set counterparty field: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListCounterparties — последние обновлённые записи.
+func ListCounterparties(db *sql.DB, limit int) ([]Counterparty, error) {
+	rows, err := db.Query(`SELECT `+counterpartyColumns+` FROM counterparties ORDER BY fetched_at DESC, inn LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Counterparty
+	for rows.Next() {
+		c, err := scanCounterparty(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+func scanCounterparty(sc interface{ Scan(...any) error }) (*Counterparty, error) {
+	var c Counterparty
+	var manual int
+	var fetchedAt int64
+	if err := sc.Scan(&c.INN, &c.KPP, &c.OGRN, &c.Name, &c.Address, &c.Source, &manual, &c.UpdatedBy, &fetchedAt); err != nil {
+		return nil, err
+	}
+	c.Manual = manual == 1
+	c.FetchedAt = time.Unix(fetchedAt, 0)
+	return &c, nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetCounterpartyFieldNeedsCachedRow(t *testing.T) {
+	db := MustOpen(filepath.Join(t.TempDir(), "test.db"))
+	defer db.Close()
+
+	updated, err := SetCounterpartyField(db, "7707083893", CounterpartyKPP, "773601001", 42)
+	if err != nil {
+		t.Fatalf("SetCounterpartyField: %v", err)
+	}
+	if updated {
+		t.Fatal("edit of an uncached INN reported as saved")
+	}
+	if _, ok, _ := GetCounterparty(db, "7707083893"); ok {
+		t.Fatal("edit of an uncached INN created a row")
+	}
+
+	if err := SaveCounterparty(db, &Counterparty{
+		INN: "7707083893", KPP: "773601001", Name: "ПАО Сбербанк", Address: "Москва", Source: "egrul", FetchedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SaveCounterparty: %v", err)
+	}
+	updated, err = SetCounterpartyField(db, "7707083893", CounterpartyAddress, "Москва, ул. Вавилова, 19", 42)
+	if err != nil || !updated {
+		t.Fatalf("SetCounterpartyField = %v, %v; want true, nil", updated, err)
+	}
+
+	c, ok, err := GetCounterparty(db, "7707083893")
+	if err != nil || !ok {
+		t.Fatalf("GetCounterparty = %v, %v", ok, err)
+	}
+	if !c.Manual || c.UpdatedBy != 42 {
+		t.Errorf("manual=%v updated_by=%d; want true, 42", c.Manual, c.UpdatedBy)
+	}
+	if c.Name != "ПАО Сбербанк" || c.KPP != "773601001" || c.Address != "Москва, ул. Вавилова, 19" {
+		t.Errorf("fields after edit: %+v", c)
+	}
+
+	if _, err := SetCounterpartyField(db, "7707083893", "ogrn", "1", 42); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
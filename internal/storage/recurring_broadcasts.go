@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RecurringBroadcast — регулярная рассылка по расписанию cron (см. пакет cron), время — по Москве.
+type RecurringBroadcast struct {
+	ID  int64
+	Bot string
+	// Key — для встроенных рассылок (например "deadline_reminder"), чтобы не создать их дважды.
+	// У созданных навигатором пустой.
+	Key  string
+	Spec string // "35 15 * * 1-5"
+
+	Text         string
+	SourceChatID int64
+	MessageIDs   []int
+	Kind         string
+	Audience     string // storage.Audience.String()
+
+	Paused    bool
+	CreatedBy int64
+	NextRunAt time.Time // zero — на паузе или расписание больше не сработает
+	LastRunAt time.Time
+}
+
+const recurringColumns = `id, bot, key, spec, text, source_chat_id, message_ids, kind, audience, paused, created_by, next_run_at, last_run_at`
+
+func CreateRecurringBroadcast(db *sql.DB, r *RecurringBroadcast) (int64, error) {
+	res, err := db.Exec(`
+INSERT INTO recurring_broadcasts (bot, key, spec, text, source_chat_id, message_ids, kind, audience, paused, created_by, next_run_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`, r.Bot, r.Key, r.Spec, r.Text, r.SourceChatID, joinInts(r.MessageIDs), r.Kind, r.Audience, boolToInt(r.Paused), r.CreatedBy, unixOrNull(r.NextRunAt))
+	if err != nil {
+		return 0, fmt.Errorf("create recurring broadcast: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create recurring broadcast: %w", err)
+	}
+	r.ID = id
+	return id, nil
+}
+
+// EnsureRecurringBroadcast создаёт встроенную рассылку (r.Key), если её ещё не было.
+// Удалённую навигатором не восстанавливает: строка остаётся с deleted=1.
+func EnsureRecurringBroadcast(db *sql.DB, r *RecurringBroadcast) (bool, error) {
+	var id int64
+	err := db.QueryRow(`SELECT id FROM recurring_broadcasts WHERE bot=? AND key=?`, r.Bot, r.Key).Scan(&id)
+	if err == nil {
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, fmt.Errorf("ensure recurring broadcast: %w", err)
+	}
+	if _, err := CreateRecurringBroadcast(db, r); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func GetRecurringBroadcast(db *sql.DB, bot string, id int64) (*RecurringBroadcast, bool, error) {
+	row := db.QueryRow(`SELECT `+recurringColumns+` FROM recurring_broadcasts WHERE id=? AND bot=? AND deleted=0`, id, bot)
+	r, err := scanRecurring(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+// ListRecurringBroadcasts — все неудалённые регулярные рассылки бота.
+func ListRecurringBroadcasts(db *sql.DB, bot string) ([]RecurringBroadcast, error) {
+	return queryRecurring(db, `SELECT `+recurringColumns+` FROM recurring_broadcasts WHERE bot=? AND deleted=0 ORDER BY id`, bot)
+}
+
+// ListDueRecurringBroadcasts — активные рассылки, у которых наступило next_run_at.
+func ListDueRecurringBroadcasts(db *sql.DB, bot string, now time.Time) ([]RecurringBroadcast, error) {
+	return queryRecurring(db, `
+SELECT `+recurringColumns+`
+FROM recurring_broadcasts
+WHERE bot=? AND deleted=0 AND paused=0 AND next_run_at IS NOT NULL AND next_run_at<=?
+ORDER BY next_run_at, id;
+`, bot, now.Unix())
+}
+
+// ClaimRecurringRun сдвигает next_run_at с prev на next. false — запуск уже забрал
+// другой процесс (или рассылку поставили на паузу/удалили).
+func ClaimRecurringRun(db *sql.DB, id int64, prev, next time.Time, ran bool) (bool, error) {
+	q := `UPDATE recurring_broadcasts SET next_run_at=? WHERE id=? AND next_run_at=? AND paused=0 AND deleted=0`
+	args := []any{unixOrNull(next), id, prev.Unix()}
+	if ran {
+		q = `UPDATE recurring_broadcasts SET next_run_at=?, last_run_at=? WHERE id=? AND next_run_at=? AND paused=0 AND deleted=0`
+		args = []any{unixOrNull(next), time.Now().Unix(), id, prev.Unix()}
+	}
+
+	res, err := db.Exec(q, args...)
+	if err != nil {
+		return false, fmt.Errorf("claim recurring run: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetRecurringPaused ставит на паузу или снимает с неё. nextRunAt — следующий запуск после снятия.
+func SetRecurringPaused(db *sql.DB, bot string, id int64, paused bool, nextRunAt time.Time) (bool, error) {
+	res, err := db.Exec(`UPDATE recurring_broadcasts SET paused=?, next_run_at=? WHERE id=? AND bot=? AND deleted=0`,
+		boolToInt(paused), unixOrNull(nextRunAt), id, bot)
+	if err != nil {
+		return false, fmt.Errorf("set recurring paused: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func DeleteRecurringBroadcast(db *sql.DB, bot string, id int64) (bool, error) {
+	res, err := db.Exec(`UPDATE recurring_broadcasts SET deleted=1, next_run_at=NULL WHERE id=? AND bot=? AND deleted=0`, id, bot)
+	if err != nil {
+		return false, fmt.Errorf("delete recurring broadcast: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func queryRecurring(db *sql.DB, q string, args ...any) ([]RecurringBroadcast, error) {
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RecurringBroadcast
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+func scanRecurring(sc interface{ Scan(...any) error }) (*RecurringBroadcast, error) {
+	var r RecurringBroadcast
+	var messageIDs string
+	var paused int
+	var nextRunAt, lastRunAt sql.NullInt64
+	if err := sc.Scan(&r.ID, &r.Bot, &r.Key, &r.Spec, &r.Text, &r.SourceChatID, &messageIDs, &r.Kind, &r.Audience,
+		&paused, &r.CreatedBy, &nextRunAt, &lastRunAt); err != nil {
+		return nil, err
+	}
+	r.MessageIDs = splitInts(messageIDs)
+	r.Paused = paused == 1
+	if nextRunAt.Valid {
+		r.NextRunAt = time.Unix(nextRunAt.Int64, 0)
+	}
+	if lastRunAt.Valid {
+		r.LastRunAt = time.Unix(lastRunAt.Int64, 0)
+	}
+	return &r, nil
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func unixOrNull(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
+
+// ReplaceBuiltinRecurringSpec меняет расписание встроенной рассылки key, только если оно
+// всё ещё равно oldSpec (навигатор его не трогал). true — строка обновлена.
+func ReplaceBuiltinRecurringSpec(db *sql.DB, bot, key, oldSpec, newSpec string, nextRunAt time.Time) (bool, error) {
+	res, err := db.Exec(`UPDATE recurring_broadcasts SET spec=?, next_run_at=CASE WHEN paused=1 THEN next_run_at ELSE ? END
+WHERE bot=? AND key=? AND spec=? AND deleted=0`, newSpec, unixOrNull(nextRunAt), bot, key, oldSpec)
+	if err != nil {
+		return false, fmt.Errorf("replace recurring spec: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SavedBuyer — покупатель из адресной книги пользователя bot3 (заполняется после отправки заявки).
+type SavedBuyer struct {
+	UserID    int64
+	INN       string
+	LegalName string
+	KPP       string // введённый вручную, если реестр его не дал
+	Contract  string
+	Uses      int
+	UsedAt    time.Time
+}
+
+// SaveBuyer — запомнить покупателя после заявки: новые данные перезаписывают старые, счётчик растёт.
+func SaveBuyer(db *sql.DB, sb *SavedBuyer) error {
+	_, err := db.Exec(`
+INSERT INTO saved_buyers (user_id, inn, legal_name, kpp, contract, uses, used_at)
+VALUES (?, ?, ?, ?, ?, 1, ?)
+ON CONFLICT(user_id, inn) DO UPDATE SET
+  legal_name=excluded.legal_name,
+  kpp=excluded.kpp,
+  contract=excluded.contract,
+  uses=saved_buyers.uses+1,
+  used_at=excluded.used_at;
+`, sb.UserID, sb.INN, sb.LegalName, sb.KPP, sb.Contract, sb.UsedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("save buyer: %w", err)
+	}
+	return nil
+}
+
+func GetSavedBuyer(db *sql.DB, userID int64, inn string) (*SavedBuyer, bool, error) {
+	row := db.QueryRow(`
+SELECT user_id, inn, legal_name, kpp, contract, uses, used_at
+FROM saved_buyers WHERE user_id=? AND inn=?`, userID, inn)
+	sb, err := scanSavedBuyer(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get saved buyer: %w", err)
+	}
+	return sb, true, nil
+}
+
+// ListSavedBuyers — последние использованные покупатели пользователя.
+func ListSavedBuyers(db *sql.DB, userID int64, limit int) ([]SavedBuyer, error) {
+	rows, err := db.Query(`
+SELECT user_id, inn, legal_name, kpp, contract, uses, used_at
+FROM saved_buyers WHERE user_id=?
+ORDER BY used_at DESC
+LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list saved buyers: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SavedBuyer
+	for rows.Next() {
+		sb, err := scanSavedBuyer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list saved buyers: %w", err)
+		}
+		out = append(out, *sb)
+	}
+	return out, rows.Err()
+}
+
+func scanSavedBuyer(s interface{ Scan(...any) error }) (*SavedBuyer, error) {
+	var sb SavedBuyer
+	var usedAt int64
+	if err := s.Scan(&sb.UserID, &sb.INN, &sb.LegalName, &sb.KPP, &sb.Contract, &sb.Uses, &usedAt); err != nil {
+		return nil, err
+	}
+	sb.UsedAt = time.Unix(usedAt, 0)
+	return &sb, nil
+}
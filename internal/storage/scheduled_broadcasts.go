@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// статусы запланированной рассылки
+const (
+	SchedStatusPending   = "pending"
+	SchedStatusSending   = "sending"
+	SchedStatusSent      = "sent"
+	SchedStatusCancelled = "cancelled"
+	SchedStatusFailed    = "failed"
+)
+
+type ScheduledBroadcast struct {
+	ID  int64
+	Bot string // "bot1" | "bot2" | "bot3"
+
+	Text           string
+	DocumentFileID string
+	PhotoFileID    string
+
+	// копия сообщений навигатора; SourceChatID == 0 — старая рассылка из полей выше
+	SourceChatID int64
+	MessageIDs   []int
+	Kind         string // "text" | "photo" | "album" | "video" | ...
+
+	Audience string // storage.Audience.String(), '' — все в базе
+
+	RunAt     time.Time
+	Status    string
+	CreatedBy int64
+	SentCount int
+}
+
+func CreateScheduledBroadcast(db *sql.DB, b *ScheduledBroadcast) (int64, error) {
+	res, err := db.Exec(`
+INSERT INTO scheduled_broadcasts (bot, text, document_file_id, photo_file_id, source_chat_id, message_ids, kind, audience, run_at, status, created_by)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`, b.Bot, b.Text, b.DocumentFileID, b.PhotoFileID, b.SourceChatID, joinInts(b.MessageIDs), b.Kind, b.Audience, b.RunAt.Unix(), SchedStatusPending, b.CreatedBy)
+	if err != nil {
+		return 0, fmt.Errorf("create scheduled broadcast: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create scheduled broadcast: %w", err)
+	}
+	b.ID = id
+	b.Status = SchedStatusPending
+	return id, nil
+}
+
+// ListPendingScheduledBroadcasts — ещё не отправленные рассылки бота, по времени отправки.
+func ListPendingScheduledBroadcasts(db *sql.DB, bot string) ([]ScheduledBroadcast, error) {
+	return queryScheduledBroadcasts(db, `
+SELECT id, bot, text, document_file_id, photo_file_id, source_chat_id, message_ids, kind, audience, run_at, status, created_by, sent_count
+FROM scheduled_broadcasts
+WHERE bot=? AND status=?
+ORDER BY run_at, id;
+`, bot, SchedStatusPending)
+}
+
+// ListDueScheduledBroadcasts — рассылки, время которых уже наступило.
+func ListDueScheduledBroadcasts(db *sql.DB, bot string, now time.Time) ([]ScheduledBroadcast, error) {
+	return queryScheduledBroadcasts(db, `
+SELECT id, bot, text, document_file_id, photo_file_id, source_chat_id, message_ids, kind, audience, run_at, status, created_by, sent_count
+FROM scheduled_broadcasts
+WHERE bot=? AND status=? AND run_at<=?
+ORDER BY run_at, id;
+`, bot, SchedStatusPending, now.Unix())
+}
+
+func queryScheduledBroadcasts(db *sql.DB, q string, args ...any) ([]ScheduledBroadcast, error) {
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScheduledBroadcast
+	for rows.Next() {
+		var b ScheduledBroadcast
+		var runAt int64
+		var messageIDs string
+		if err := rows.Scan(&b.ID, &b.Bot, &b.Text, &b.DocumentFileID, &b.PhotoFileID, &b.SourceChatID, &messageIDs, &b.Kind, &b.Audience, &runAt, &b.Status, &b.CreatedBy, &b.SentCount); err != nil {
+			return nil, err
+		}
+		b.RunAt = time.Unix(runAt, 0)
+		b.MessageIDs = splitInts(messageIDs)
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// ClaimScheduledBroadcast переводит рассылку pending -> sending.
+// Возвращает false, если её уже забрал другой процесс или отменили.
+func ClaimScheduledBroadcast(db *sql.DB, id int64) (bool, error) {
+	res, err := db.Exec(`UPDATE scheduled_broadcasts SET status=? WHERE id=? AND status=?`,
+		SchedStatusSending, id, SchedStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("claim scheduled broadcast: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func FinishScheduledBroadcast(db *sql.DB, id int64, status string, sentCount int) error {
+	_, err := db.Exec(`
+UPDATE scheduled_broadcasts
+SET status=?, sent_count=?, sent_at=?
+WHERE id=?;
+`, status, sentCount, time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("finish scheduled broadcast: %w", err)
+	}
+	return nil
+}
+
+func CancelScheduledBroadcast(db *sql.DB, bot string, id int64) (bool, error) {
+	res, err := db.Exec(`UPDATE scheduled_broadcasts SET status=? WHERE id=? AND bot=? AND status=?`,
+		SchedStatusCancelled, id, bot, SchedStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("cancel scheduled broadcast: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// FailInterruptedScheduledBroadcasts помечает failed рассылки, которые остались в sending
+// (процесс упал посреди отправки). Повторно не шлём, чтобы не было дублей.
+func FailInterruptedScheduledBroadcasts(db *sql.DB, bot string) (int64, error) {
+	res, err := db.Exec(`UPDATE scheduled_broadcasts SET status=? WHERE bot=? AND status=?`,
+		SchedStatusFailed, bot, SchedStatusSending)
+	if err != nil {
+		return 0, fmt.Errorf("fail interrupted scheduled broadcasts: %w", err)
+	}
+	return res.RowsAffected()
+}
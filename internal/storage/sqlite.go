@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"strconv"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
@@ -89,5 +91,264 @@ CREATE TABLE IF NOT EXISTS invoice_seq (
 		return err
 	}
 
+	// ✅ заявки bot3, ожидающие подтверждения (переживают рестарт)
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS applications (
+  id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+  invoice_no          INTEGER NOT NULL,
+  invoice_date        INTEGER NOT NULL,
+  user_chat_id        INTEGER NOT NULL,
+  user_message_id     INTEGER NOT NULL,
+  text                TEXT NOT NULL DEFAULT '',
+  draft_json          TEXT NOT NULL DEFAULT '',
+  status              TEXT NOT NULL DEFAULT 'pending',
+  approval_chat_id    INTEGER NOT NULL,
+  approval_message_id INTEGER NOT NULL,
+  xlsx_path           TEXT NOT NULL DEFAULT '',
+  pdf_path            TEXT NOT NULL DEFAULT '',
+  temp_dir            TEXT NOT NULL DEFAULT '',
+  created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(approval_chat_id, approval_message_id)
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	// ✅ черновики мастера заявки bot3 (переживают рестарт)
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS app_drafts (
+  telegram_id INTEGER PRIMARY KEY,
+  state_json  TEXT NOT NULL,
+  updated_at  INTEGER NOT NULL
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	// ✅ запланированные рассылки навигатора (переживают рестарт)
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS scheduled_broadcasts (
+  id               INTEGER PRIMARY KEY AUTOINCREMENT,
+  bot              TEXT NOT NULL,
+  text             TEXT NOT NULL DEFAULT '',
+  document_file_id TEXT NOT NULL DEFAULT '',
+  photo_file_id    TEXT NOT NULL DEFAULT '',
+  run_at           INTEGER NOT NULL,
+  status           TEXT NOT NULL DEFAULT 'pending',
+  created_by       INTEGER NOT NULL DEFAULT 0,
+  sent_count       INTEGER NOT NULL DEFAULT 0,
+  sent_at          INTEGER,
+  created_at       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_scheduled_broadcasts_due ON scheduled_broadcasts(bot, status, run_at);`)
+	if err != nil {
+		return err
+	}
+
+	// ✅ боты, которым пользователь недоступен (заблокировал бота и т.п.): ",bot1,bot3,".
+	// Отдельно от blocked — тот ставят сотрудники. Таблица users общая, поэтому по ботам.
+	if err := addColumnIfMissing(db, "users", "unreachable_bots", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+
+	// ✅ когда пользователь последний раз писал боту (unix) — для аудитории «активные за N дней»
+	if err := addColumnIfMissing(db, "users", "last_seen_at", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	// ✅ аудитория запланированной рассылки (storage.Audience.String), '' — все в базе
+	if err := addColumnIfMissing(db, "scheduled_broadcasts", "audience", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+
+	// ✅ журнал рассылок: одна строка на рассылку + по строке на получателя
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS broadcasts (
+  id               INTEGER PRIMARY KEY AUTOINCREMENT,
+  bot              TEXT NOT NULL,
+  scheduled_id     INTEGER NOT NULL DEFAULT 0,
+  text             TEXT NOT NULL DEFAULT '',
+  document_file_id TEXT NOT NULL DEFAULT '',
+  photo_file_id    TEXT NOT NULL DEFAULT '',
+  created_by       INTEGER NOT NULL DEFAULT 0,
+  total            INTEGER NOT NULL DEFAULT 0,
+  sent             INTEGER NOT NULL DEFAULT 0,
+  failed           INTEGER NOT NULL DEFAULT 0,
+  started_at       INTEGER NOT NULL,
+  finished_at      INTEGER
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS broadcast_deliveries (
+  broadcast_id INTEGER NOT NULL,
+  chat_id      INTEGER NOT NULL,
+  message_id   INTEGER NOT NULL DEFAULT 0,
+  error        TEXT NOT NULL DEFAULT '',
+  sent_at      INTEGER NOT NULL,
+  PRIMARY KEY (broadcast_id, chat_id)
+);
+`)
+	if err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "broadcasts", "audience", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+
+	// ✅ регулярные рассылки (cron, по Москве); встроенные (напоминание о дедлайне) — с key
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS recurring_broadcasts (
+  id             INTEGER PRIMARY KEY AUTOINCREMENT,
+  bot            TEXT NOT NULL,
+  key            TEXT NOT NULL DEFAULT '',
+  spec           TEXT NOT NULL,
+  text           TEXT NOT NULL DEFAULT '',
+  source_chat_id INTEGER NOT NULL DEFAULT 0,
+  message_ids    TEXT NOT NULL DEFAULT '',
+  kind           TEXT NOT NULL DEFAULT '',
+  audience       TEXT NOT NULL DEFAULT '',
+  paused         INTEGER NOT NULL DEFAULT 0,
+  deleted        INTEGER NOT NULL DEFAULT 0,
+  created_by     INTEGER NOT NULL DEFAULT 0,
+  next_run_at    INTEGER,
+  last_run_at    INTEGER,
+  created_at     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_recurring_broadcasts_due ON recurring_broadcasts(bot, next_run_at);`)
+	if err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "broadcasts", "recurring_id", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	// ✅ кэш контрагентов по ИНН (поиск по реестрам — медленный и может забанить IP)
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS counterparties (
+  inn        TEXT PRIMARY KEY,
+  kpp        TEXT NOT NULL DEFAULT '',
+  ogrn       TEXT NOT NULL DEFAULT '',
+  name       TEXT NOT NULL DEFAULT '',
+  address    TEXT NOT NULL DEFAULT '',
+  source     TEXT NOT NULL DEFAULT '',
+  manual     INTEGER NOT NULL DEFAULT 0,
+  updated_by INTEGER NOT NULL DEFAULT 0,
+  fetched_at INTEGER NOT NULL
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	// ✅ адресная книга bot3: покупатели, по которым пользователь уже отправлял заявки
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS saved_buyers (
+  user_id    INTEGER NOT NULL,
+  inn        TEXT NOT NULL,
+  legal_name TEXT NOT NULL DEFAULT '',
+  kpp        TEXT NOT NULL DEFAULT '',
+  contract   TEXT NOT NULL DEFAULT '',
+  uses       INTEGER NOT NULL DEFAULT 0,
+  used_at    INTEGER NOT NULL,
+  PRIMARY KEY (user_id, inn)
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	// ✅ отклонённая заявка хранит причину, исправленная — ссылку на отклонённую (parent_id)
+	if err := addColumnIfMissing(db, "applications", "reject_reason", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "applications", "parent_id", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	// ✅ рассылка как копия сообщений навигатора (copyMessage/copyMessages):
+	// source_chat_id + message_ids ("101,102,103"), kind — что это было (для списков и отчётов)
+	for _, table := range []string{"scheduled_broadcasts", "broadcasts"} {
+		if err := addColumnIfMissing(db, table, "source_chat_id", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(db, table, "message_ids", `TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(db, table, "kind", `TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// addColumnIfMissing — ALTER TABLE ADD COLUMN для уже существующих БД (в SQLite нет IF NOT EXISTS для колонок).
+func addColumnIfMissing(db *sql.DB, table, column, ddl string) error {
+	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid     int
+			name    string
+			typ     string
+			notNull int
+			dflt    sql.NullString
+			pk      int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if strings.EqualFold(name, column) {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + ddl)
+	return err
+}
+
+// joinInts / splitInts: []int <-> "1,2,3" для хранения в TEXT.
+func joinInts(xs []int) string {
+	parts := make([]string, 0, len(xs))
+	for _, x := range xs {
+		parts = append(parts, strconv.Itoa(x))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitInts(s string) []int {
+	var out []int
+	for _, p := range strings.Split(s, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func placeholders(n int) string {
+	return strings.TrimRight(strings.Repeat("?,", n), ",")
+}
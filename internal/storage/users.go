@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type User struct {
@@ -16,26 +17,58 @@ type User struct {
 	Company    int
 	Allowed    bool
 	Blocked    bool
+
+	// Bot — через какого бота пользователь сейчас написал. UpsertUser снимает
+	// для этого бота отметку «недоступен»: раз пишет — значит, не заблокировал.
+	Bot string
 }
 
 func UpsertUser(db *sql.DB, u *User) error {
 	_, err := db.Exec(`
-INSERT INTO users (telegram_id, chat_id, username, first_name, last_name)
-VALUES (?, ?, ?, ?, ?)
+INSERT INTO users (telegram_id, chat_id, username, first_name, last_name, last_seen_at)
+VALUES (?, ?, ?, ?, ?, ?)
 ON CONFLICT(telegram_id) DO UPDATE SET
   chat_id=excluded.chat_id,
   username=excluded.username,
   first_name=excluded.first_name,
-  last_name=excluded.last_name;
-`, u.TelegramID, u.ChatID, u.Username, u.FirstName, u.LastName)
+  last_name=excluded.last_name,
+  last_seen_at=excluded.last_seen_at,
+  unreachable_bots=replace(unreachable_bots, ?, ',');
+`, u.TelegramID, u.ChatID, u.Username, u.FirstName, u.LastName, time.Now().Unix(), botMark(u.Bot))
 	if err != nil {
 		return fmt.Errorf("upsert user: %w", err)
 	}
 	return nil
 }
 
-func ListAllUserChatIDs(db *sql.DB) ([]int64, error) {
-	rows, err := db.Query(`SELECT chat_id FROM users`)
+// botMark: ",bot1," — так имя бота хранится в unreachable_bots.
+func botMark(bot string) string {
+	if bot == "" {
+		return ",\x00," // пустой бот ничего не снимает
+	}
+	return "," + bot + ","
+}
+
+// MarkUserUnreachable: бот получил 403 (пользователь заблокировал бота и т.п.) — больше ему не пишем,
+// пока пользователь сам не напишет этому боту.
+func MarkUserUnreachable(db *sql.DB, bot string, chatID int64) (bool, error) {
+	res, err := db.Exec(`
+UPDATE users
+SET unreachable_bots = CASE WHEN unreachable_bots = '' THEN ',' ELSE unreachable_bots END || ? || ','
+WHERE chat_id = ? AND instr(unreachable_bots, ?) = 0;
+`, bot, chatID, botMark(bot))
+	if err != nil {
+		return false, fmt.Errorf("mark user unreachable: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func ListAllUserChatIDs(db *sql.DB, bot string) ([]int64, error) {
+	rows, err := db.Query(`SELECT chat_id FROM users WHERE instr(unreachable_bots, ?) = 0`, botMark(bot))
 	if err != nil {
 		return nil, err
 	}
@@ -205,12 +238,12 @@ LIMIT 1
 	return chatID, true, nil
 }
 
-func ListAllowedUserChatIDs(db *sql.DB) ([]int64, error) {
+func ListAllowedUserChatIDs(db *sql.DB, bot string) ([]int64, error) {
 	rows, err := db.Query(`
 SELECT chat_id
 FROM users
-WHERE allowed = 1
-`)
+WHERE allowed = 1 AND instr(unreachable_bots, ?) = 0
+`, botMark(bot))
 	if err != nil {
 		return nil, err
 	}
@@ -227,12 +260,12 @@ WHERE allowed = 1
 	return out, nil
 }
 
-func ListAllowedNotBlockedUserChatIDs(db *sql.DB) ([]int64, error) {
+func ListAllowedNotBlockedUserChatIDs(db *sql.DB, bot string) ([]int64, error) {
 	rows, err := db.Query(`
 SELECT chat_id
 FROM users
-WHERE allowed = 1 AND blocked = 0
-`)
+WHERE allowed = 1 AND blocked = 0 AND instr(unreachable_bots, ?) = 0
+`, botMark(bot))
 	if err != nil {
 		return nil, err
 	}